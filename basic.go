@@ -8,26 +8,104 @@ const DefaultVer = "2.0.0"
 // DefaultUA Default User-Agent
 const DefaultUA = "MiniRequest/" + DefaultVer
 
+// APIKey Send an API key as a header or query param
+type APIKey struct {
+	Key   string
+	Value string
+	In    string // "header" (default) or "query"
+}
+
 // Auth Set HTTP Basic Auth
 type Auth []string
 
+// BytesBody Use raw bytes, ContentType defaults to application/octet-stream
+type BytesBody struct {
+	Data        []byte
+	ContentType string
+}
+
+// ChannelBody Stream the body from Chunks until it is closed, the request is never replayed
+type ChannelBody struct {
+	Chunks      <-chan []byte
+	ContentType string
+}
+
+// ContextValue Attach a value to the request context
+type ContextValue struct {
+	Key   any
+	Value any
+}
+
+// WithContextValue Attach key/value to the request context
+func WithContextValue(key, value any) ContextValue {
+	return ContextValue{Key: key, Value: value}
+}
+
 // Cookies Set Cookies
 type Cookies []*http.Cookie
 
+// ExpectContentType Fail when the response media type differs, e.g. "application/json"
+//
+// The response is closed on failure, see ContentTypeError.Snippet.
+type ExpectContentType string
+
+// FileBody Stream a file as the raw body, reopened for replays
+//
+// It is never compressed by EnableCompression.
+type FileBody struct {
+	Path        string
+	ContentType string // defaults to application/octet-stream
+}
+
 // FormData Use multipart/form-data
 type FormData struct {
 	Values map[string]string
 	Files  map[string]string
+	Gzip   bool // compress the body, sent with Content-Encoding: gzip
 }
 
 // FormData Use application/x-www-from-urlencoded
 type FormKV map[string]string
 
+// ForceChunked Send the body chunked even when its length is known
+type ForceChunked bool
+
 // Headers Set Header
 type Headers map[string]string
 
+// Host Send this Host header instead of the url host, see SetTLSServerName for SNI
+type Host string
+
+// IfMatch Only apply the request if the resource still has etag
+func IfMatch(etag string) Headers {
+	return Headers{"If-Match": etag}
+}
+
+// IfNoneMatch Only apply the request if the resource no longer has etag
+func IfNoneMatch(etag string) Headers {
+	return Headers{"If-None-Match": etag}
+}
+
 // JSONData Use application/json
 type JSONData map[string]any
 
+// NoCompress Send the body uncompressed despite EnableCompression
+type NoCompress bool
+
+// NoCookies Neither send nor store jar cookies for this request, Cookies still apply
+type NoCookies bool
+
+// NoDecompress Return the body exactly as sent, still gzip-compressed if it was
+type NoDecompress bool
+
 // Params Set Params
 type Params map[string]string
+
+// PathParams Fill {name} placeholders of the url path with escaped values
+type PathParams map[string]string
+
+// PreEncodedForm Send an already encoded application/x-www-form-urlencoded body byte for byte
+type PreEncodedForm string
+
+// RemoveHeaders Drop these headers, client defaults included, from this request
+type RemoveHeaders []string
@@ -0,0 +1,122 @@
+package minireq
+
+import (
+	"context"
+	"time"
+)
+
+// RequestBuilder Chainable request, maps onto the option types
+type RequestBuilder struct {
+	client  *HttpClient
+	ctx     context.Context // nil until Context, the client's at Send
+	method  string
+	url     string
+	timeout time.Duration
+	headers Headers
+	params  Params
+	opts    []any
+}
+
+// NewRequest Start a chainable request
+func (h *HttpClient) NewRequest() *RequestBuilder {
+	return &RequestBuilder{
+		client: h,
+		method: "GET",
+	}
+}
+
+// Method Set request method
+func (b *RequestBuilder) Method(method string) *RequestBuilder {
+	b.method = method
+	return b
+}
+
+// URL Set request url
+func (b *RequestBuilder) URL(url string) *RequestBuilder {
+	b.url = url
+	return b
+}
+
+// Context Bind the request to ctx
+func (b *RequestBuilder) Context(ctx context.Context) *RequestBuilder {
+	b.ctx = ctx
+	return b
+}
+
+// Timeout Set a timeout for this request only
+func (b *RequestBuilder) Timeout(d time.Duration) *RequestBuilder {
+	b.timeout = d
+	return b
+}
+
+// Header Set a header
+func (b *RequestBuilder) Header(key, value string) *RequestBuilder {
+	if b.headers == nil {
+		b.headers = make(Headers)
+	}
+	b.headers[key] = value
+	return b
+}
+
+// Param Set a query param
+func (b *RequestBuilder) Param(key, value string) *RequestBuilder {
+	if b.params == nil {
+		b.params = make(Params)
+	}
+	b.params[key] = value
+	return b
+}
+
+// Auth Use HTTP Basic Auth
+func (b *RequestBuilder) Auth(username, password string) *RequestBuilder {
+	return b.Option(Auth{username, password})
+}
+
+// JSON Use application/json
+func (b *RequestBuilder) JSON(data JSONData) *RequestBuilder {
+	return b.Option(data)
+}
+
+// Form Use application/x-www-from-urlencoded
+func (b *RequestBuilder) Form(data FormKV) *RequestBuilder {
+	return b.Option(data)
+}
+
+// FormData Use multipart/form-data
+func (b *RequestBuilder) FormData(data FormData) *RequestBuilder {
+	return b.Option(data)
+}
+
+// Option Append any option accepted by Request
+func (b *RequestBuilder) Option(opt any) *RequestBuilder {
+	b.opts = append(b.opts, opt)
+	return b
+}
+
+// Send Send the request
+func (b *RequestBuilder) Send() (*MiniResponse, error) {
+	opts := make([]any, 0, len(b.opts)+2)
+	opts = append(opts, b.opts...)
+	if b.params != nil {
+		opts = append(opts, b.params)
+	}
+	if b.headers != nil {
+		opts = append(opts, b.headers)
+	}
+
+	// resolved now so a CancelAll since NewRequest does not apply
+	ctx := b.ctx
+	if ctx == nil {
+		ctx = b.client.baseContext()
+	}
+	if b.timeout > 0 {
+		ctx, cancel := context.WithTimeout(ctx, b.timeout)
+		return b.client.requestWithCancel(ctx, cancel, b.method, b.url, opts...)
+	}
+	return b.client.RequestWithContext(ctx, b.method, b.url, opts...)
+}
+
+// Do Alias of Send
+func (b *RequestBuilder) Do() (*MiniResponse, error) {
+	return b.Send()
+}
@@ -0,0 +1,93 @@
+package minireq
+
+import (
+	"net/http"
+	"time"
+)
+
+// ClientConfig Snapshot of the settings a client uses
+type ClientConfig struct {
+	Timeout                time.Duration // effective per-attempt timeout
+	ReadTimeout            time.Duration
+	FirstByteTimeout       time.Duration
+	AutoRedirectDisable    bool
+	CustomCheckRedirect    bool // SetCheckRedirect overrides the redirect settings
+	RecordRedirects        bool
+	RedirectSameHostOnly   bool
+	RedirectDenyPrivateIPs bool
+	ForwardAuthOnRedirect  bool
+	ForwardAuthSameDomain  bool
+
+	Socks5Address    string
+	ProxyFunc        bool // SetProxyFunc is in use
+	Insecure         bool
+	TLSServerName    string
+	H2C              bool
+	HeaderOrder      []string
+	PerHostTransport bool
+	CustomTransport  bool // SetTransport is in use
+	TLSHandshake     bool // SetTLSHandshake is in use
+	IdleConnTimeout  time.Duration
+	ProtoMajor       int
+	ProtoMinor       int
+
+	BodyReadRetries       int
+	MaxConcurrentRequests int
+	AuthRefresher         bool
+	ErrorBodySnippetLen   int
+	SpaceAsPercent        bool
+	URLNormalization      bool
+	TrailingSlash         string
+	ForceHTTPS            bool
+	RejectHTTP            bool
+	Compress              bool
+	Headers               http.Header // defaults set by SetHeader
+}
+
+// Config Current settings, for diagnostics
+//
+// Changing the snapshot does not affect the client.
+func (h *HttpClient) Config() ClientConfig {
+	timeout := h.Timeout
+	if timeout == 0 {
+		timeout = 30
+	}
+	cfg := ClientConfig{
+		Timeout:                time.Duration(timeout) * time.Second,
+		ReadTimeout:            h.ReadTimeout,
+		FirstByteTimeout:       h.FirstByteTimeout,
+		AutoRedirectDisable:    h.AutoRedirectDisable,
+		CustomCheckRedirect:    h.CheckRedirect != nil,
+		RecordRedirects:        h.RecordRedirects,
+		RedirectSameHostOnly:   h.RedirectSameHostOnly,
+		RedirectDenyPrivateIPs: h.RedirectDenyPrivateIPs,
+		ForwardAuthOnRedirect:  h.ForwardAuthOnRedirect,
+		ForwardAuthSameDomain:  h.ForwardAuthSameDomain,
+		BodyReadRetries:        h.BodyReadRetries,
+		ErrorBodySnippetLen:    h.snippetLen(),
+		SpaceAsPercent:         h.SpaceAsPercent,
+		URLNormalization:       h.URLNormalization,
+		TrailingSlash:          h.TrailingSlash,
+		ForceHTTPS:             h.ForceHTTPS,
+		RejectHTTP:             h.RejectHTTP,
+		Compress:               h.Compress,
+		Headers:                h.defaultHeaders(),
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	cfg.Socks5Address = h.Socks5Address
+	cfg.Insecure = h.Insecure
+	cfg.TLSServerName = h.TLSServerName
+	cfg.H2C = h.H2C
+	cfg.HeaderOrder = append([]string(nil), h.HeaderOrder...)
+	cfg.PerHostTransport = h.PerHostTransport
+	cfg.IdleConnTimeout = time.Duration(h.IdleConnTimeout) * time.Second
+	cfg.ProtoMajor, cfg.ProtoMinor = h.ProtoMajor, h.ProtoMinor
+	cfg.ProxyFunc = h.proxyFunc != nil
+	cfg.CustomTransport = h.transport != nil
+	cfg.TLSHandshake = h.tlsHandshake != nil
+	cfg.MaxConcurrentRequests = h.MaxConcurrentRequests
+	cfg.AuthRefresher = h.refresher != nil
+	return cfg
+}
@@ -0,0 +1,178 @@
+package minireq
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// FromCurl Build a request from a curl command, e.g. a browser "copy as cURL"
+//
+// Supported flags: -X, -H, -d and its --data variants, -u, -b, --url, plus
+// the no-ops --compressed, -s, -L. Any other flag is an error.
+func (h *HttpClient) FromCurl(curlCmd string) (*http.Request, error) {
+	args, err := splitShell(curlCmd)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 0 || args[0] != "curl" {
+		return nil, errors.New("not a curl command")
+	}
+
+	var method, url string
+	var headers [][2]string
+	var data []string
+	var user string
+	hasData := false
+
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		value := func() (string, error) {
+			if i+1 >= len(args) {
+				return "", fmt.Errorf("curl flag %s needs a value", arg)
+			}
+			i++
+			return args[i], nil
+		}
+
+		switch arg {
+		case "-X", "--request":
+			if method, err = value(); err != nil {
+				return nil, err
+			}
+		case "-H", "--header":
+			header, err := value()
+			if err != nil {
+				return nil, err
+			}
+			key, val, ok := strings.Cut(header, ":")
+			if !ok {
+				return nil, fmt.Errorf("invalid curl header %q", header)
+			}
+			headers = append(headers, [2]string{strings.TrimSpace(key), strings.TrimSpace(val)})
+		case "-d", "--data", "--data-raw", "--data-binary", "--data-ascii":
+			d, err := value()
+			if err != nil {
+				return nil, err
+			}
+			if strings.HasPrefix(d, "@") && arg != "--data-raw" {
+				return nil, fmt.Errorf("curl data from file is not supported: %s", d)
+			}
+			data = append(data, d)
+			hasData = true
+		case "-u", "--user":
+			if user, err = value(); err != nil {
+				return nil, err
+			}
+		case "-b", "--cookie":
+			cookie, err := value()
+			if err != nil {
+				return nil, err
+			}
+			headers = append(headers, [2]string{"Cookie", cookie})
+		case "--url":
+			if url, err = value(); err != nil {
+				return nil, err
+			}
+		case "--compressed", "-s", "--silent", "-L", "--location":
+		default:
+			if strings.HasPrefix(arg, "-") {
+				return nil, fmt.Errorf("unsupported curl flag %s", arg)
+			}
+			url = arg
+		}
+	}
+
+	if url == "" {
+		return nil, errors.New("curl command has no url")
+	}
+	if method == "" {
+		method = "GET"
+		if hasData {
+			method = "POST"
+		}
+	}
+
+	var body *strings.Reader
+	if hasData {
+		body = strings.NewReader(strings.Join(data, "&"))
+	}
+	var request *http.Request
+	if body != nil {
+		request, err = http.NewRequest(method, url, body)
+	} else {
+		request, err = http.NewRequest(method, url, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, header := range headers {
+		request.Header.Add(header[0], header[1])
+	}
+	if hasData && request.Header.Get("Content-Type") == "" {
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if user != "" {
+		username, password, _ := strings.Cut(user, ":")
+		request.SetBasicAuth(username, password)
+	}
+	return request, nil
+}
+
+// splitShell split a command line like a POSIX shell, without expansion
+func splitShell(s string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArg := false
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\':
+			if i+1 < len(s) {
+				i++
+				// line continuation
+				if s[i] == '\n' {
+					continue
+				}
+				current.WriteByte(s[i])
+				inArg = true
+			}
+		case c == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("unterminated single quote")
+			}
+			current.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+			inArg = true
+		case c == '"':
+			i++
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte("\"\\$`", s[i+1]) >= 0 {
+					i++
+				}
+				current.WriteByte(s[i])
+			}
+			if i >= len(s) {
+				return nil, errors.New("unterminated double quote")
+			}
+			inArg = true
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteByte(c)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,106 @@
+package minireq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+)
+
+var (
+	// ErrTimeout Request timed out
+	ErrTimeout = errors.New("request timeout")
+	// ErrDNSFailure Host lookup failed
+	ErrDNSFailure = errors.New("dns failure")
+	// ErrConnectionRefused Remote refused the connection
+	ErrConnectionRefused = errors.New("connection refused")
+	// ErrProxyFailure Proxy unreachable or rejected the request
+	ErrProxyFailure = errors.New("proxy failure")
+	// ErrReadTimeout A body Read exceeded SetReadTimeout
+	ErrReadTimeout = errors.New("body read timeout")
+	// ErrBodyTaken The body was handed over by MiniResponse.Body
+	ErrBodyTaken = errors.New("response body taken by Body()")
+	// ErrQuotaExceeded The client read its SetDownloadQuota
+	ErrQuotaExceeded = errors.New("download quota exceeded")
+	// ErrPlaintextURL An http url was refused by SetRejectHTTP
+	ErrPlaintextURL = errors.New("plaintext http url")
+	// ErrRedirectBlocked Redirect rejected by the host policy
+	ErrRedirectBlocked = errors.New("redirect blocked")
+	// ErrTLSHandshakeProxy SetTLSHandshake combined with an HTTP proxy
+	ErrTLSHandshakeProxy = errors.New("tls handshake hook not supported with an http proxy")
+	// ErrHeaderOrderProxy SetHeaderOrder combined with an HTTP proxy
+	ErrHeaderOrderProxy = errors.New("header order not supported with an http proxy")
+)
+
+// RequestError Failure class with the original error
+//
+// errors.Is matches the class sentinel, errors.Unwrap returns the original.
+type RequestError struct {
+	Kind error
+	Err  error
+}
+
+func (e *RequestError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+func (e *RequestError) Is(target error) bool {
+	return target == e.Kind
+}
+
+// classifyError wrap known transport failures, others are returned as is
+func classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var kind error
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &opErr) && (strings.HasPrefix(opErr.Op, "socks") || opErr.Op == "proxyconnect"):
+		kind = ErrProxyFailure
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		kind = ErrTimeout
+	case errors.As(err, &dnsErr):
+		kind = ErrDNSFailure
+	case errors.Is(err, syscall.ECONNREFUSED):
+		kind = ErrConnectionRefused
+	default:
+		return err
+	}
+	return &RequestError{Kind: kind, Err: err}
+}
+
+// ContentTypeError Response media type differs from ExpectContentType
+type ContentTypeError struct {
+	Expected string
+	Actual   string
+	Snippet  string // start of the body
+}
+
+func (e *ContentTypeError) Error() string {
+	return fmt.Sprintf("unexpected content type %q, want %q: %s", e.Actual, e.Expected, e.Snippet)
+}
+
+// StatusError Non-2xx response
+type StatusError struct {
+	StatusCode int
+	Status     string // e.g. "404 Not Found"
+	Message    string // message extracted from the body, may be empty
+	Snippet    string // start of the body
+}
+
+func (e *StatusError) Error() string {
+	if e.Message == "" {
+		return e.Status
+	}
+	return e.Status + ": " + e.Message
+}
@@ -0,0 +1,41 @@
+// Package fingerprint Browser-like TLS fingerprints for minireq via uTLS
+package fingerprint
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+
+	"github.com/qmaru/minireq/v2"
+	utls "github.com/refraction-networking/utls"
+)
+
+// SetTLSClientHelloSpec Make client's TLS handshakes send the ClientHello of id, e.g. utls.HelloChrome_Auto
+//
+// ALPN is limited to http/1.1 because net/http cannot run HTTP/2 over a uTLS
+// connection.
+func SetTLSClientHelloSpec(client *minireq.HttpClient, id utls.ClientHelloID) {
+	client.SetTLSHandshake(func(ctx context.Context, conn net.Conn, cfg *tls.Config) (net.Conn, error) {
+		spec, err := utls.UTLSIdToSpec(id)
+		if err != nil {
+			return nil, err
+		}
+		for _, ext := range spec.Extensions {
+			if alpn, ok := ext.(*utls.ALPNExtension); ok {
+				alpn.AlpnProtocols = []string{"http/1.1"}
+			}
+		}
+
+		uconn := utls.UClient(conn, &utls.Config{
+			ServerName:         cfg.ServerName,
+			InsecureSkipVerify: cfg.InsecureSkipVerify,
+		}, utls.HelloCustom)
+		if err := uconn.ApplyPreset(&spec); err != nil {
+			return nil, err
+		}
+		if err := uconn.HandshakeContext(ctx); err != nil {
+			return nil, err
+		}
+		return uconn, nil
+	})
+}
@@ -0,0 +1,45 @@
+package fingerprint
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/qmaru/minireq/v2"
+	utls "github.com/refraction-networking/utls"
+)
+
+func TestSetTLSClientHelloSpec(t *testing.T) {
+	var suites int
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Proto)
+	}))
+	server.TLS = &tls.Config{
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			suites = len(hello.CipherSuites)
+			return nil, nil
+		},
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	client := minireq.NewClient()
+	client.SetInsecure(true)
+	SetTLSClientHelloSpec(client, utls.HelloChrome_Auto)
+	res, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := res.RawData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Chrome offers a GREASE value and 15 cipher suites
+	if string(data) == "HTTP/1.1" && suites == 16 {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %s with %d suites", data, suites)
+	}
+}
@@ -0,0 +1,160 @@
+package minireq
+
+import (
+	"errors"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// ErrFormNotFound No form matched the selector
+var ErrFormNotFound = errors.New("form not found")
+
+// SubmitForm Fetch a page, fill a form with its current values and extra, then POST it
+//
+// selector picks the form: "" for the first one, "#id" by id, otherwise by
+// name. Hidden inputs such as CSRF tokens are submitted as found, cookies set
+// by the page are sent back through the client's jar. opts apply to both requests.
+func (h *HttpClient) SubmitForm(url string, selector string, extra FormKV, opts ...any) (*MiniResponse, error) {
+	page, err := h.RequestWithMethod("GET", url, opts...)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := html.Parse(page.Response.Body)
+	// frees the concurrency slot before the POST
+	page.Response.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	form := findForm(doc, selector)
+	if form == nil {
+		return nil, ErrFormNotFound
+	}
+
+	values := make(FormKV)
+	collectFields(form, values)
+	for k, v := range extra {
+		values[k] = v
+	}
+
+	// relative to the page as served, after redirects
+	action, err := page.Response.Request.URL.Parse(attr(form, "action"))
+	if err != nil {
+		return nil, err
+	}
+
+	postOpts := append(append(make([]any, 0, len(opts)+1), opts...), values)
+	return h.RequestWithMethod("POST", action.String(), postOpts...)
+}
+
+// findForm first form matching selector
+func findForm(n *html.Node, selector string) *html.Node {
+	if n.Type == html.ElementNode && n.Data == "form" {
+		switch {
+		case selector == "":
+			return n
+		case strings.HasPrefix(selector, "#"):
+			if attr(n, "id") == selector[1:] {
+				return n
+			}
+		case attr(n, "name") == selector:
+			return n
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if form := findForm(c, selector); form != nil {
+			return form
+		}
+	}
+	return nil
+}
+
+// collectFields values a browser would submit without user input
+func collectFields(n *html.Node, values FormKV) {
+	if n.Type == html.ElementNode {
+		name := attr(n, "name")
+		switch {
+		case name == "" || hasAttr(n, "disabled"):
+		case n.Data == "input":
+			switch strings.ToLower(attr(n, "type")) {
+			case "submit", "button", "image", "reset", "file":
+			case "checkbox", "radio":
+				if hasAttr(n, "checked") {
+					value := attr(n, "value")
+					if value == "" {
+						value = "on"
+					}
+					values[name] = value
+				}
+			default:
+				values[name] = attr(n, "value")
+			}
+		case n.Data == "textarea":
+			if n.FirstChild != nil {
+				values[name] = n.FirstChild.Data
+			} else {
+				values[name] = ""
+			}
+		case n.Data == "select":
+			values[name] = selectedOption(n)
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		collectFields(c, values)
+	}
+}
+
+// selectedOption value of the selected option, else the first one
+func selectedOption(n *html.Node) string {
+	var first *html.Node
+	var walk func(*html.Node) *html.Node
+	walk = func(n *html.Node) *html.Node {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && c.Data == "option" {
+				if first == nil {
+					first = c
+				}
+				if hasAttr(c, "selected") {
+					return c
+				}
+			}
+			if found := walk(c); found != nil {
+				return found
+			}
+		}
+		return nil
+	}
+
+	option := walk(n)
+	if option == nil {
+		option = first
+	}
+	if option == nil {
+		return ""
+	}
+	if hasAttr(option, "value") {
+		return attr(option, "value")
+	}
+	if option.FirstChild != nil {
+		return strings.TrimSpace(option.FirstChild.Data)
+	}
+	return ""
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func hasAttr(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
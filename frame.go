@@ -0,0 +1,70 @@
+package minireq
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// maxFrameSize Larger frames are rejected
+const maxFrameSize = 64 << 20
+
+// FrameDecoder Read the length prefix of the next frame, io.EOF when the stream ends
+type FrameDecoder func(r io.Reader) (int, error)
+
+// Uint32BigEndian 4-byte big-endian length prefix
+func Uint32BigEndian(r io.Reader) (int, error) {
+	var prefix [4]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return 0, err
+	}
+	return int(binary.BigEndian.Uint32(prefix[:])), nil
+}
+
+// FrameReader Read length-prefixed frames from a response body
+type FrameReader struct {
+	body   io.ReadCloser
+	decode FrameDecoder
+	err    error // ErrBodyTaken, the body belongs to the Body caller
+}
+
+// FrameReader Read the body as frames, the caller must Close it
+func (res *MiniResponse) FrameReader(decode FrameDecoder) *FrameReader {
+	if res.bodyTaken {
+		return &FrameReader{err: ErrBodyTaken}
+	}
+	return &FrameReader{
+		body:   res.Response.Body,
+		decode: decode,
+	}
+}
+
+// Next Payload of the next frame, io.EOF after the last one
+func (f *FrameReader) Next() ([]byte, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	size, err := f.decode(f.body)
+	if err != nil {
+		return nil, err
+	}
+	if size < 0 || size > maxFrameSize {
+		return nil, fmt.Errorf("invalid frame size %d", size)
+	}
+	frame := make([]byte, size)
+	if _, err := io.ReadFull(f.body, frame); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return frame, nil
+}
+
+// Close Close the body
+func (f *FrameReader) Close() error {
+	if f.body == nil {
+		return nil
+	}
+	return f.body.Close()
+}
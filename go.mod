@@ -2,4 +2,22 @@ module github.com/qmaru/minireq/v2
 
 go 1.20
 
-require golang.org/x/net v0.34.0
+require (
+	github.com/gorilla/websocket v1.5.3
+	github.com/refraction-networking/utls v1.6.0
+	github.com/vmihailenco/msgpack/v5 v5.4.1
+	golang.org/x/net v0.34.0
+	golang.org/x/sync v0.10.0
+	google.golang.org/protobuf v1.34.2
+)
+
+require (
+	github.com/andybalholm/brotli v1.0.5 // indirect
+	github.com/cloudflare/circl v1.3.6 // indirect
+	github.com/klauspost/compress v1.16.7 // indirect
+	github.com/quic-go/quic-go v0.37.4 // indirect
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+	golang.org/x/crypto v0.32.0 // indirect
+	golang.org/x/sys v0.29.0 // indirect
+	golang.org/x/text v0.21.0 // indirect
+)
@@ -0,0 +1,185 @@
+package minireq
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"sort"
+	"strconv"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+// orderedTransport HTTP/1.1 transport writing headers in a fixed order
+//
+// Each request uses its own connection.
+type orderedTransport struct {
+	order       []string
+	dialContext func(ctx context.Context, network, address string) (net.Conn, error)
+	tlsConfig   *tls.Config
+	handshake   *TLSHandshake
+}
+
+func (t *orderedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	closeBody := func() {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+	}
+
+	if err := validateRequest(req); err != nil {
+		closeBody()
+		return nil, err
+	}
+
+	addr := req.URL.Host
+	if req.URL.Port() == "" {
+		port := "80"
+		if req.URL.Scheme == "https" {
+			port = "443"
+		}
+		addr = net.JoinHostPort(req.URL.Hostname(), port)
+	}
+
+	ctx := req.Context()
+	conn, err := t.dialContext(ctx, "tcp", addr)
+	if err != nil {
+		closeBody()
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if req.URL.Scheme == "https" {
+		cfg := t.tlsConfig.Clone()
+		if cfg.ServerName == "" {
+			cfg.ServerName = req.URL.Hostname()
+		}
+		cfg.NextProtos = []string{"http/1.1"}
+		var tlsConn net.Conn
+		if t.handshake != nil {
+			tlsConn, err = (*t.handshake)(ctx, conn, cfg)
+		} else {
+			c := tls.Client(conn, cfg)
+			tlsConn, err = c, c.HandshakeContext(ctx)
+		}
+		if err != nil {
+			conn.Close()
+			closeBody()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	if err := t.writeRequest(conn, req); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	response, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	response.Body = &connBody{ReadCloser: response.Body, conn: conn}
+	return response, nil
+}
+
+// validateRequest reject what http.Transport would, nothing is written unchecked
+func validateRequest(req *http.Request) error {
+	if !httpguts.ValidHeaderFieldName(req.Method) {
+		return fmt.Errorf("invalid method %q", req.Method)
+	}
+	if req.Host != "" && !httpguts.ValidHostHeader(req.Host) {
+		return fmt.Errorf("invalid Host header %q", req.Host)
+	}
+	for name, values := range req.Header {
+		if !httpguts.ValidHeaderFieldName(name) {
+			return fmt.Errorf("invalid header field name %q", name)
+		}
+		for _, v := range values {
+			if !httpguts.ValidHeaderFieldValue(v) {
+				return fmt.Errorf("invalid header field value for %q", name)
+			}
+		}
+	}
+	return nil
+}
+
+// writeRequest write the request line, ordered headers and body
+func (t *orderedTransport) writeRequest(w io.Writer, req *http.Request) error {
+	if req.Body != nil {
+		defer req.Body.Close()
+	}
+
+	header := req.Header.Clone()
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	header.Set("Host", host)
+	header.Set("Connection", "close")
+	chunked := req.Body != nil && req.ContentLength <= 0
+	if chunked {
+		header.Set("Transfer-Encoding", "chunked")
+	} else if req.Body != nil || req.Method == "POST" || req.Method == "PUT" {
+		header.Set("Content-Length", strconv.FormatInt(req.ContentLength, 10))
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%s %s HTTP/1.1\r\n", req.Method, req.URL.RequestURI())
+
+	written := make(map[string]bool)
+	writeHeader := func(name string) {
+		key := http.CanonicalHeaderKey(name)
+		if written[key] {
+			return
+		}
+		written[key] = true
+		for _, v := range header[key] {
+			fmt.Fprintf(bw, "%s: %s\r\n", name, v)
+		}
+	}
+	for _, name := range t.order {
+		writeHeader(name)
+	}
+	rest := make([]string, 0, len(header))
+	for key := range header {
+		rest = append(rest, key)
+	}
+	sort.Strings(rest)
+	for _, key := range rest {
+		writeHeader(key)
+	}
+	bw.WriteString("\r\n")
+
+	if req.Body != nil {
+		if chunked {
+			cw := httputil.NewChunkedWriter(bw)
+			if _, err := io.Copy(cw, req.Body); err != nil {
+				return err
+			}
+			cw.Close()
+			bw.WriteString("\r\n")
+		} else if _, err := io.Copy(bw, req.Body); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// connBody close the connection with the body
+type connBody struct {
+	io.ReadCloser
+	conn net.Conn
+}
+
+func (b *connBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.conn.Close()
+	return err
+}
@@ -2,23 +2,33 @@ package minireq
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"crypto/tls"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"mime/multipart"
 	"net"
 	"net/http"
 	"net/http/cookiejar"
+	"net/http/httptrace"
 	URL "net/url"
 	"os"
 	"path/filepath"
+	"reflect"
 	"regexp"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
+	"golang.org/x/net/http2"
 	"golang.org/x/net/proxy"
+	"golang.org/x/net/publicsuffix"
+	"golang.org/x/sync/semaphore"
 )
 
 type HttpClient struct {
@@ -26,11 +36,107 @@ type HttpClient struct {
 	AutoRedirectDisable bool   // automatic redirection
 	Socks5Address       string // socks5 proxy addr
 	Insecure            bool   // allow insecure request
+	TLSServerName       string // SNI and verified name, empty means the url host
 	Timeout             int    // request timeout
+	H2C                 bool   // HTTP/2 prior knowledge over cleartext
+	BodyReadRetries     int    // replay the request when reading the body fails
+	PerHostTransport    bool   // separate connection pool per scheme://host
+	ErrorBodySnippetLen int    // body bytes quoted in errors, 0 means 512
+	SpaceAsPercent      bool   // encode spaces in Params as %20 instead of +
+	IdleConnTimeout     int    // seconds an idle connection stays pooled, 0 means no limit
+	Compress            bool   // gzip request bodies, see EnableCompression
+	ProtoMajor          int    // HTTP version, 0 lets the transport negotiate
+	ProtoMinor          int
+	URLNormalization    bool   // collapse duplicate slashes in the path
+	ForceHTTPS          bool   // upgrade http urls, redirects included, to https
+	RejectHTTP          bool   // fail http urls instead of upgrading them
+	TrailingSlash       string // "add" or "strip" with URLNormalization, empty keeps it
+
+	ReadTimeout      time.Duration // max wait for data per body Read, 0 means none
+	FirstByteTimeout time.Duration // max wait for the first body byte, 0 means none
+
+	HeaderOrder     []string // write headers in this order over HTTP/1.1
+	RecordRedirects bool     // keep the redirect chain on the response
+
+	RedirectSameHostOnly   bool // block redirects leaving the original host
+	RedirectDenyPrivateIPs bool // block redirects to private or loopback addresses
+
+	ForwardAuthOnRedirect bool // keep Authorization on redirects to the same host
+	ForwardAuthSameDomain bool // widen ForwardAuthOnRedirect to the registrable domain
+
+	CheckRedirect func(req *http.Request, via []*http.Request) error // overrides the redirect settings
+
+	MaxConcurrentRequests int // max in-flight requests, 0 means unlimited
+
+	mu           sync.Mutex
+	ctx          context.Context
+	cancel       context.CancelFunc
+	sem          *semaphore.Weighted
+	encoders     map[reflect.Type]Encoder
+	transportCfg transportConfig
+	transports   map[string]http.RoundTripper
+	proxyFunc    *ProxyFunc
+	proxyPool    *proxyPool
+	proxyHeader  *http.Header
+	transport    http.RoundTripper
+	tlsHandshake *TLSHandshake
+	jar          http.CookieJar
+	headers      http.Header
+
+	downloaded atomic.Int64
+	quota      atomic.Int64
+
+	refreshMu sync.Mutex
+	refresher func(ctx context.Context) error
+	transform func(io.Reader) (io.Reader, error)
+	marshal   func(any) ([]byte, error)
+	unmarshal func([]byte, any) error
+	authGen   uint64
+}
+
+// ProxyFunc Choose the proxy for a request, nil url means direct
+type ProxyFunc func(*http.Request) (*URL.URL, error)
+
+// TLSHandshake Run the TLS handshake on conn, cfg carries ServerName and InsecureSkipVerify
+type TLSHandshake func(ctx context.Context, conn net.Conn, cfg *tls.Config) (net.Conn, error)
+
+// Encoder Apply a custom option type to the request
+type Encoder func(request *http.Request, opt any) error
+
+var (
+	defaultsMu sync.RWMutex
+	defaults   func(*HttpClient)
+)
+
+// SetDefaults Configure every client created by NewClient afterwards
+//
+// Setters called on a client still override the defaults. nil clears them.
+func SetDefaults(fn func(*HttpClient)) {
+	defaultsMu.Lock()
+	defer defaultsMu.Unlock()
+	defaults = fn
 }
 
 func NewClient() *HttpClient {
 	client := new(HttpClient)
+	client.IdleConnTimeout = 90
+
+	defaultsMu.RLock()
+	fn := defaults
+	defaultsMu.RUnlock()
+	if fn != nil {
+		fn(client)
+	}
+	return client
+}
+
+// NewClientWithTransport Create a client sending through t, shared with other clients
+//
+// Clients keep their own cookie jar, timeout and headers while reusing the
+// connection pool of t. Proxy and TLS settings come from t, see SetTransport.
+func NewClientWithTransport(t http.RoundTripper) *HttpClient {
+	client := NewClient()
+	client.SetTransport(t)
 	return client
 }
 
@@ -53,15 +159,131 @@ func setProxy(address string) (proxy.Dialer, error) {
 	return dialer, nil
 }
 
+// proxyDialContext dial through the socks5 proxy, nil without proxy
+func (h *HttpClient) proxyDialContext() (func(ctx context.Context, network, address string) (net.Conn, error), error) {
+	if h.Socks5Address == "" {
+		return nil, nil
+	}
+	dialer, err := setProxy(h.Socks5Address)
+	if err != nil {
+		return nil, &RequestError{Kind: ErrProxyFailure, Err: err}
+	}
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		return dialer.Dial(network, address)
+	}, nil
+}
+
+// h2cTransport speak HTTP/2 over plain TCP, https urls use the fallback
+type h2cTransport struct {
+	h2       *http2.Transport
+	fallback http.RoundTripper
+}
+
+func newH2CTransport(fallback *http.Transport) *h2cTransport {
+	dialContext := fallback.DialContext
+	if dialContext == nil {
+		dialContext = (&net.Dialer{}).DialContext
+	}
+	return &h2cTransport{
+		h2: &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return dialContext(ctx, network, addr)
+			},
+		},
+		fallback: fallback,
+	}
+}
+
+func (t *h2cTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme == "http" {
+		return t.h2.RoundTrip(req)
+	}
+	return t.fallback.RoundTrip(req)
+}
+
+func (t *h2cTransport) CloseIdleConnections() {
+	t.h2.CloseIdleConnections()
+	t.fallback.(*http.Transport).CloseIdleConnections()
+}
+
+// copyBufPool buffers for copying upload files into the multipart body
+var copyBufPool = sync.Pool{
+	New: func() any {
+		buf := make([]byte, 32<<10)
+		return &buf
+	},
+}
+
 // reqOptions construct a body
-func reqOptions(request *http.Request, opts any) (*http.Request, error) {
+func (h *HttpClient) reqOptions(request *http.Request, opts any) (*http.Request, error) {
 	switch t := opts.(type) {
+	case APIKey:
+		switch t.In {
+		case "", "header":
+			request.Header.Set(t.Key, t.Value)
+		case "query":
+			// applied once Params set the query
+		default:
+			return nil, fmt.Errorf("unknown api key location %q", t.In)
+		}
 	case Auth:
 		request.SetBasicAuth(t[0], t[1])
+	case BytesBody:
+		contentType := t.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		reader := bytes.NewReader(t.Data)
+		snapshot := *reader
+
+		request.Header.Set("Content-Type", contentType)
+		request.ContentLength = int64(reader.Len())
+		request.Body = io.NopCloser(reader)
+		request.GetBody = func() (io.ReadCloser, error) {
+			r := snapshot
+			return io.NopCloser(&r), nil
+		}
+	case ChannelBody:
+		contentType := t.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		request.Header.Set("Content-Type", contentType)
+		request.ContentLength = -1
+		request.Body = io.NopCloser(&chanReader{chunks: t.Chunks})
+		request.GetBody = nil
+	case ContextValue:
+		request = request.WithContext(context.WithValue(request.Context(), t.Key, t.Value))
 	case Cookies:
 		for _, c := range t {
 			request.AddCookie(c)
 		}
+	case ExpectContentType:
+		// checked once the response arrives
+	case ForceChunked:
+		// applied once the body is set
+	case FileBody:
+		contentType := t.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		f, err := os.Open(t.Path)
+		if err != nil {
+			return nil, err
+		}
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+
+		request.Header.Set("Content-Type", contentType)
+		request.ContentLength = info.Size()
+		request.Body = f
+		request.GetBody = func() (io.ReadCloser, error) {
+			return os.Open(t.Path)
+		}
 	case FormData:
 		bodyBuf := &bytes.Buffer{}
 		bodyWriter := multipart.NewWriter(bodyBuf)
@@ -91,7 +313,10 @@ func reqOptions(request *http.Request, opts any) (*http.Request, error) {
 				if err != nil {
 					return nil, err
 				}
-				_, err = io.Copy(fileWriter, f)
+				// hide os.File.WriteTo so the pooled buffer is used
+				buf := copyBufPool.Get().(*[]byte)
+				_, err = io.CopyBuffer(fileWriter, struct{ io.Reader }{f}, *buf)
+				copyBufPool.Put(buf)
 				if err != nil {
 					return nil, err
 				}
@@ -103,6 +328,15 @@ func reqOptions(request *http.Request, opts any) (*http.Request, error) {
 			return nil, err
 		}
 
+		if t.Gzip {
+			zipped, err := gzipBytes(bodyBuf.Bytes())
+			if err != nil {
+				return nil, err
+			}
+			bodyBuf = bytes.NewBuffer(zipped)
+			request.Header.Set("Content-Encoding", "gzip")
+		}
+
 		reader := bytes.NewBuffer(bodyBuf.Bytes())
 		buf := reader.Bytes()
 
@@ -132,8 +366,10 @@ func reqOptions(request *http.Request, opts any) (*http.Request, error) {
 		for k, v := range t {
 			request.Header.Set(k, v)
 		}
+	case Host:
+		request.Host = string(t)
 	case JSONData:
-		jsonByte, err := json.Marshal(t)
+		jsonByte, err := h.jsonMarshaler()(t)
 		if err != nil {
 			return nil, err
 		}
@@ -147,154 +383,1288 @@ func reqOptions(request *http.Request, opts any) (*http.Request, error) {
 			r := snapshot
 			return io.NopCloser(&r), nil
 		}
+	case NoCompress:
+		// applied once the body is set
+	case NoCookies:
+		// applied when the request is sent
+	case NoDecompress:
+		// net/http only decompresses when it added Accept-Encoding itself
+		if t && request.Header.Get("Accept-Encoding") == "" {
+			request.Header.Set("Accept-Encoding", "gzip")
+		}
+	case RemoveHeaders:
+		// applied once every header is set
 	case Params:
 		query := make(URL.Values)
 		for k, v := range t {
 			query.Add(k, v)
 		}
-		request.URL.RawQuery = query.Encode()
+		request.URL.RawQuery = h.encodeQuery(query)
+	case PathParams:
+		// expanded before the url is parsed
+	case PreEncodedForm:
+		reader := strings.NewReader(string(t))
+		snapshot := *reader
+
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		request.ContentLength = int64(reader.Len())
+		request.Body = io.NopCloser(reader)
+		request.GetBody = func() (io.ReadCloser, error) {
+			r := snapshot
+			return io.NopCloser(&r), nil
+		}
+	default:
+		if encoder := h.encoder(reflect.TypeOf(opts)); encoder != nil {
+			if err := encoder(request, opts); err != nil {
+				return nil, err
+			}
+		}
 	}
 	return request, nil
 }
 
+// chanReader read chunks from a channel until it is closed
+type chanReader struct {
+	chunks <-chan []byte
+	buf    []byte
+}
+
+func (r *chanReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		chunk, ok := <-r.chunks
+		if !ok {
+			return 0, io.EOF
+		}
+		r.buf = chunk
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// RegisterEncoder Teach the client to apply options of the same type as sampleType
+//
+// Built-in option types always use their own encoding.
+func (h *HttpClient) RegisterEncoder(sampleType any, fn Encoder) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.encoders == nil {
+		h.encoders = make(map[reflect.Type]Encoder)
+	}
+	h.encoders[reflect.TypeOf(sampleType)] = fn
+}
+
+// encoder registered encoder for t, nil if none
+func (h *HttpClient) encoder(t reflect.Type) Encoder {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.encoders[t]
+}
+
+// expand replace the placeholders in url
+func (p PathParams) expand(url string) string {
+	pairs := make([]string, 0, len(p)*2)
+	for k, v := range p {
+		pairs = append(pairs, "{"+k+"}", URL.PathEscape(v))
+	}
+	return strings.NewReplacer(pairs...).Replace(url)
+}
+
+// encodeQuery encode Params, sorted by key
+func (h *HttpClient) encodeQuery(query URL.Values) string {
+	encoded := query.Encode()
+	if h.SpaceAsPercent {
+		// QueryEscape turns a literal + into %2B, so every + is a space
+		encoded = strings.ReplaceAll(encoded, "+", "%20")
+	}
+	return encoded
+}
+
+// cookieJar jar shared by every request of the client
+func (h *HttpClient) cookieJar() (http.CookieJar, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.jar == nil {
+		jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+		if err != nil {
+			return nil, err
+		}
+		h.jar = jar
+	}
+	return h.jar, nil
+}
+
+// SetCookie Store a cookie for u, it is sent on every later matching request
+func (h *HttpClient) SetCookie(u *URL.URL, cookie *http.Cookie) error {
+	jar, err := h.cookieJar()
+	if err != nil {
+		return err
+	}
+	jar.SetCookies(u, []*http.Cookie{cookie})
+	return nil
+}
+
+// SetCookieValue Store a name=value cookie for u
+func (h *HttpClient) SetCookieValue(u *URL.URL, name, value string) error {
+	return h.SetCookie(u, &http.Cookie{Name: name, Value: value})
+}
+
+// SetHeader Set a header sent with every request, options can override it
+func (h *HttpClient) SetHeader(key, value string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.headers == nil {
+		h.headers = make(http.Header)
+	}
+	h.headers.Set(key, value)
+}
+
+// SetAcceptLanguage Send Accept-Language with every request, tags in order of preference
+//
+// Later tags get decreasing q weights, e.g. "en-US,en;q=0.9,fr;q=0.8". No
+// tags removes the header.
+func (h *HttpClient) SetAcceptLanguage(tags ...string) {
+	if len(tags) == 0 {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.headers.Del("Accept-Language")
+		return
+	}
+	parts := make([]string, len(tags))
+	for i, tag := range tags {
+		q := 10 - i
+		if q < 1 {
+			q = 1
+		}
+		if q == 10 {
+			parts[i] = tag
+		} else {
+			parts[i] = fmt.Sprintf("%s;q=0.%d", tag, q)
+		}
+	}
+	h.SetHeader("Accept-Language", strings.Join(parts, ","))
+}
+
+// defaultHeaders copy of the headers set by SetHeader
+func (h *HttpClient) defaultHeaders() http.Header {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.headers == nil {
+		return make(http.Header)
+	}
+	return h.headers.Clone()
+}
+
+// SetAuthRefresher Refresh credentials once and replay the request on 401
+//
+// fn typically fetches a new token and stores it with SetHeader. Concurrent
+// 401s share a single refresh, requests whose body cannot be replayed
+// return the 401 as is.
+func (h *HttpClient) SetAuthRefresher(fn func(ctx context.Context) error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.refresher = fn
+}
+
+// SetResponseBodyTransform Decode every response body through fn, e.g. base64 or decryption
+//
+// fn runs on the first read of the body, after decompression, and its error
+// is returned by that read. nil removes the transform.
+func (h *HttpClient) SetResponseBodyTransform(fn func(io.Reader) (io.Reader, error)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.transform = fn
+}
+
+// bodyTransform the transform set by SetResponseBodyTransform
+func (h *HttpClient) bodyTransform() func(io.Reader) (io.Reader, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.transform
+}
+
+// SetJSONMarshaler Encode JSONData and PostJSON bodies with fn, nil restores encoding/json
+func (h *HttpClient) SetJSONMarshaler(fn func(any) ([]byte, error)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.marshal = fn
+}
+
+// SetJSONUnmarshaler Decode RawJSON and BindJSON with fn, nil restores encoding/json
+func (h *HttpClient) SetJSONUnmarshaler(fn func([]byte, any) error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.unmarshal = fn
+}
+
+// jsonMarshaler the marshaler set by SetJSONMarshaler, json.Marshal by default
+func (h *HttpClient) jsonMarshaler() func(any) ([]byte, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.marshal == nil {
+		return json.Marshal
+	}
+	return h.marshal
+}
+
+// jsonUnmarshaler the unmarshaler set by SetJSONUnmarshaler, json.Unmarshal by default
+func (h *HttpClient) jsonUnmarshaler() func([]byte, any) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.unmarshal == nil {
+		return json.Unmarshal
+	}
+	return h.unmarshal
+}
+
+// CookieJar The jar holding the client's cookies
+func (h *HttpClient) CookieJar() (http.CookieJar, error) {
+	return h.cookieJar()
+}
+
+// TransferCookiesTo Copy the cookies h would send to u into other's jar
+//
+// The jar only exposes name and value. The copies are host-only session
+// cookies for u with Path "/", marked Secure when u is https. The original
+// domain, path, expiry, HttpOnly and SameSite are lost.
+func (h *HttpClient) TransferCookiesTo(other *HttpClient, u *URL.URL) error {
+	from, err := h.cookieJar()
+	if err != nil {
+		return err
+	}
+	to, err := other.cookieJar()
+	if err != nil {
+		return err
+	}
+	cookies := from.Cookies(u)
+	for _, c := range cookies {
+		c.Path = "/"
+		c.Secure = u.Scheme == "https"
+	}
+	to.SetCookies(u, cookies)
+	return nil
+}
+
 // SetTimeout Set timeout
 func (h *HttpClient) SetTimeout(t int) {
 	h.Timeout = t
 }
 
+// SetProtocolVersion Pin the HTTP version to 1.0, 1.1 or 2.0, 0.0 negotiates again
+//
+// net/http always writes an HTTP/1.1 request line, 1.0 sends Connection: close
+// and never reuses connections. 1.1 turns HTTP/2 off over TLS and 2.0 turns it
+// on even with custom TLS settings, use SetH2C for cleartext HTTP/2.
+func (h *HttpClient) SetProtocolVersion(major, minor int) error {
+	switch {
+	case major == 0 && minor == 0, major == 1 && (minor == 0 || minor == 1), major == 2 && minor == 0:
+	default:
+		return fmt.Errorf("unsupported protocol version %d.%d", major, minor)
+	}
+	h.ProtoMajor, h.ProtoMinor = major, minor
+	return nil
+}
+
+// protoVersion the pinned HTTP version, read under h.mu for SetTransportConfig
+func (h *HttpClient) protoVersion() (int, int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.ProtoMajor, h.ProtoMinor
+}
+
+// SetForceHTTPS Send http urls as https and ws urls as wss, redirect targets included
+func (h *HttpClient) SetForceHTTPS(t bool) {
+	h.ForceHTTPS = t
+}
+
+// SetRejectHTTP Fail http and ws urls with ErrPlaintextURL, takes precedence over SetForceHTTPS
+func (h *HttpClient) SetRejectHTTP(t bool) {
+	h.RejectHTTP = t
+}
+
+// secureURL apply RejectHTTP and ForceHTTPS to u
+func (h *HttpClient) secureURL(u *URL.URL) error {
+	secure, ok := map[string]string{"http": "https", "ws": "wss"}[strings.ToLower(u.Scheme)]
+	if !ok {
+		return nil
+	}
+	if h.RejectHTTP {
+		return fmt.Errorf("%w: %s", ErrPlaintextURL, u.Redacted())
+	}
+	if h.ForceHTTPS {
+		u.Scheme = secure
+		if u.Port() == "80" {
+			u.Host = u.Hostname()
+			if strings.Contains(u.Host, ":") {
+				u.Host = "[" + u.Host + "]"
+			}
+		}
+	}
+	return nil
+}
+
+// SetURLNormalization Collapse duplicate slashes in request paths
+//
+// Off by default, changing the path breaks signed URLs. See SetTrailingSlash.
+func (h *HttpClient) SetURLNormalization(t bool) {
+	h.URLNormalization = t
+}
+
+// SetTrailingSlash Add or strip the trailing slash of normalized paths, mode is "add", "strip" or ""
+func (h *HttpClient) SetTrailingSlash(mode string) error {
+	switch mode {
+	case "", "add", "strip":
+		h.TrailingSlash = mode
+		return nil
+	}
+	return fmt.Errorf("unknown trailing slash mode %q", mode)
+}
+
+// normalizePath collapse duplicate slashes and apply the trailing slash mode
+func normalizePath(path, trailingSlash string) string {
+	if path == "" {
+		if trailingSlash == "add" {
+			return "/"
+		}
+		return path
+	}
+	path = duplicateSlashes.ReplaceAllString(path, "/")
+	switch trailingSlash {
+	case "add":
+		if !strings.HasSuffix(path, "/") {
+			path += "/"
+		}
+	case "strip":
+		if path != "/" {
+			path = strings.TrimSuffix(path, "/")
+		}
+	}
+	return path
+}
+
+var duplicateSlashes = regexp.MustCompile(`//+`)
+
+// EnableCompression Gzip request bodies and accept gzip responses
+//
+// Replayable bodies without a Content-Encoding are sent compressed, NoCompress
+// opts a request out and FileBody is streamed as is. Responses are requested
+// with Accept-Encoding: gzip and decompressed transparently, as without this
+// setting, NoDecompress opts out.
+func (h *HttpClient) EnableCompression() {
+	h.Compress = true
+}
+
+// SetIdleConnTimeout Close pooled connections idle for t seconds
+//
+// NewClient starts at 90 seconds. 0 keeps the http.Transport default, which
+// never closes idle connections.
+func (h *HttpClient) SetIdleConnTimeout(t int) {
+	h.IdleConnTimeout = t
+}
+
 // SetProxy Set socks5 proxy
 func (h *HttpClient) SetProxy(addr string) {
 	h.Socks5Address = addr
 }
 
+// SetProxyFunc Choose the proxy per request, takes precedence over SetProxy
+func (h *HttpClient) SetProxyFunc(fn ProxyFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.proxyPool = nil
+	if fn == nil {
+		h.proxyFunc = nil
+	} else {
+		h.proxyFunc = &fn
+	}
+}
+
+// SetProxyConnectHeader Send header on the CONNECT request to proxies from SetProxyFunc
+func (h *HttpClient) SetProxyConnectHeader(header http.Header) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if header == nil {
+		h.proxyHeader = nil
+	} else {
+		header = header.Clone()
+		h.proxyHeader = &header
+	}
+}
+
+// SetTransport Send every request through t instead of the built-in transports
+//
+// Proxy, TLS and pool settings no longer apply, options, redirects and
+// replays still do. Useful to inject a mock in tests. nil restores the default.
+func (h *HttpClient) SetTransport(t http.RoundTripper) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.transport = t
+}
+
+// SetTLSHandshake Replace the TLS handshake, e.g. to mimic a browser fingerprint
+//
+// The connection is dialed through the socks5 proxy first and HeaderOrder is
+// honored. HTTP proxies from SetProxyFunc or SetProxyPool are not supported,
+// requests fail with ErrTLSHandshakeProxy. See the fingerprint subpackage for
+// a uTLS implementation. nil restores crypto/tls.
+func (h *HttpClient) SetTLSHandshake(fn TLSHandshake) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if fn == nil {
+		h.tlsHandshake = nil
+	} else {
+		h.tlsHandshake = &fn
+	}
+}
+
 // SetInsecure Allow Insecure
 func (h *HttpClient) SetInsecure(t bool) {
 	h.Insecure = t
 }
 
+// SetTLSServerName Present name in SNI and verify the certificate against it
+//
+// Pair with the Host option to reach a virtual host by IP address.
+func (h *HttpClient) SetTLSServerName(name string) {
+	h.TLSServerName = name
+}
+
 // SetAutoRedirectDisable Disable Redirect
 func (h *HttpClient) SetAutoRedirectDisable(t bool) {
 	h.AutoRedirectDisable = t
 }
 
+// SetCheckRedirect Use fn as http.Client.CheckRedirect, ignoring the other redirect settings
+func (h *HttpClient) SetCheckRedirect(fn func(req *http.Request, via []*http.Request) error) {
+	h.CheckRedirect = fn
+}
+
+// SetForwardAuthOnRedirect Resend the original Authorization header on same host redirects
+func (h *HttpClient) SetForwardAuthOnRedirect(t bool) {
+	h.ForwardAuthOnRedirect = t
+}
+
+// SetForwardAuthSameDomain Also resend it within the same registrable domain, e.g. api.example.com -> example.com
+func (h *HttpClient) SetForwardAuthSameDomain(t bool) {
+	h.ForwardAuthSameDomain = t
+}
+
+// SetRecordRedirects Record each followed redirect, see MiniResponse.RedirectChain
+func (h *HttpClient) SetRecordRedirects(t bool) {
+	h.RecordRedirects = t
+}
+
+// SetRedirectHostPolicy Restrict where redirects may go, for fetching user-supplied urls
+//
+// Private targets are checked by resolving the host, which cannot rule out a
+// DNS answer changing before the connection is made.
+func (h *HttpClient) SetRedirectHostPolicy(allowCrossHost bool, denyPrivateIPs bool) {
+	h.RedirectSameHostOnly = !allowCrossHost
+	h.RedirectDenyPrivateIPs = denyPrivateIPs
+}
+
+// SetH2C Use HTTP/2 without TLS for http:// urls
+func (h *HttpClient) SetH2C(t bool) {
+	h.H2C = t
+}
+
+// SetEncodeSpaceAsPercent Encode spaces in Params as %20, for APIs signing the exact query
+func (h *HttpClient) SetEncodeSpaceAsPercent(t bool) {
+	h.SpaceAsPercent = t
+}
+
+// SetPerHostTransport Keep a separate connection pool for each destination
+//
+// A slow host then cannot exhaust the idle connections used by the others.
+func (h *HttpClient) SetPerHostTransport(t bool) {
+	h.PerHostTransport = t
+}
+
+// SetErrorBodySnippetLen Bytes of the body quoted in response errors, defaults to 512
+//
+// The snippet is peeked, the full body stays readable from the response.
+func (h *HttpClient) SetErrorBodySnippetLen(n int) {
+	h.ErrorBodySnippetLen = n
+}
+
+// snippetLen body bytes quoted in errors
+func (h *HttpClient) snippetLen() int {
+	if h.ErrorBodySnippetLen > 0 {
+		return h.ErrorBodySnippetLen
+	}
+	return defaultSnippetLen
+}
+
+// SetHeaderOrder Write headers in this order, for servers fingerprinting it
+//
+// Names are written as given, unlisted headers follow sorted. Requests then
+// go over HTTP/1.1 on a new connection each and H2C is ignored. HTTP proxies
+// from SetProxyFunc or SetProxyPool are not supported, requests fail with
+// ErrHeaderOrderProxy instead of bypassing them.
+func (h *HttpClient) SetHeaderOrder(order []string) {
+	h.HeaderOrder = order
+}
+
+// SetBodyReadRetries Buffer the body and replay the request up to n times on transient read errors
+//
+// The response is only returned once its body has been read completely.
+// Requests whose body cannot be replayed are sent once.
+func (h *HttpClient) SetBodyReadRetries(n int) {
+	h.BodyReadRetries = n
+}
+
+// SetMaxConcurrentRequests Limit in-flight requests across all goroutines sharing the client
+//
+// A slot is held from the start of a request until its body is closed, the
+// replay after an auth refresh reuses it. n <= 0 removes the limit.
+func (h *HttpClient) SetMaxConcurrentRequests(n int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.MaxConcurrentRequests = n
+	if n > 0 {
+		h.sem = semaphore.NewWeighted(int64(n))
+	} else {
+		h.sem = nil
+	}
+}
+
+// limiter returns the concurrency gate, nil if unlimited
+func (h *HttpClient) limiter() *semaphore.Weighted {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.sem
+}
+
+// acquireSlot wait for a free slot, release may be called more than once
+func (h *HttpClient) acquireSlot(ctx context.Context) (release func(), err error) {
+	sem := h.limiter()
+	if sem == nil {
+		return func() {}, nil
+	}
+	if err := sem.Acquire(ctx, 1); err != nil {
+		return nil, err
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() { sem.Release(1) })
+	}, nil
+}
+
+// holdSlot keep the slot until the body of res is closed
+func holdSlot(res *MiniResponse, release func()) *MiniResponse {
+	if res == nil {
+		release()
+		return nil
+	}
+	res.Response.Body = &cancelBody{ReadCloser: res.Response.Body, cancel: release}
+	return res
+}
+
+// SetDownloadQuota Cap the body bytes read across all responses, 0 removes the cap
+//
+// Reads past the cap fail with ErrQuotaExceeded. Concurrent reads may overshoot
+// by at most one read buffer each.
+func (h *HttpClient) SetDownloadQuota(bytes int64) {
+	h.quota.Store(bytes)
+}
+
+// BytesDownloaded Body bytes read across all responses of the client
+func (h *HttpClient) BytesDownloaded() int64 {
+	return h.downloaded.Load()
+}
+
+// baseContext parent of requests without a caller context, ended by CancelAll
+func (h *HttpClient) baseContext() context.Context {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.ctx == nil {
+		h.ctx, h.cancel = context.WithCancel(context.Background())
+	}
+	return h.ctx
+}
+
+// CancelAll Cancel in-flight requests and close idle connections
+//
+// Covers every request not given its own context, RequestWithContext and
+// RequestBuilder.Context callers cancel theirs. Requests started afterwards
+// proceed normally.
+func (h *HttpClient) CancelAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.cancel != nil {
+		h.cancel()
+		h.ctx, h.cancel = nil, nil
+	}
+	h.closeIdleTransports()
+	if t, ok := h.transport.(interface{ CloseIdleConnections() }); ok {
+		t.CloseIdleConnections()
+	}
+}
+
 // Request Universal client
 func (h *HttpClient) Request(url string, opts ...any) (*MiniResponse, error) {
+	return h.RequestWithMethod(h.Method, url, opts...)
+}
+
+// RequestWithMethod Universal client with explicit method, safe for concurrent use
+func (h *HttpClient) RequestWithMethod(method, url string, opts ...any) (*MiniResponse, error) {
+	return h.RequestWithContext(h.baseContext(), method, url, opts...)
+}
+
+// BuildRequest Construct the request Request would send, without sending it
+//
+// The request carries context.Background, CancelAll does not reach it once
+// passed to Do. Use WithContext to bind it.
+func (h *HttpClient) BuildRequest(method, url string, opts ...any) (*http.Request, error) {
+	return h.buildRequest(context.Background(), method, url, opts...)
+}
+
+// RequestBody Bytes request will send, read from GetBody so the body stays unread
+//
+// nil without a body. Bodies that can't be replayed, like ChannelBody, are an
+// error.
+func RequestBody(request *http.Request) ([]byte, error) {
+	if request.Body == nil || request.Body == http.NoBody {
+		return nil, nil
+	}
+	if request.GetBody == nil {
+		return nil, errors.New("request body can't be replayed")
+	}
+	body, err := request.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+// buildRequest apply options and default headers
+func (h *HttpClient) buildRequest(ctx context.Context, method, url string, opts ...any) (*http.Request, error) {
 	var err error
 	// Make URL
+	for _, opt := range opts {
+		if params, ok := opt.(PathParams); ok {
+			url = params.expand(url)
+		}
+	}
 	parseURL, err := URL.Parse(url)
 	if err != nil {
 		return nil, err
 	}
+	if err := h.secureURL(parseURL); err != nil {
+		return nil, err
+	}
+	if h.URLNormalization {
+		parseURL.Path = normalizePath(parseURL.Path, h.TrailingSlash)
+		if parseURL.RawPath != "" {
+			parseURL.RawPath = normalizePath(parseURL.RawPath, h.TrailingSlash)
+		}
+	}
 	// Make Request
 	request := &http.Request{
 		URL:    parseURL,
-		Method: h.Method,
-		Header: make(http.Header),
+		Method: method,
+		Header: h.defaultHeaders(),
+	}
+	request = request.WithContext(ctx)
+	if major, minor := h.protoVersion(); major != 0 {
+		request.Proto = fmt.Sprintf("HTTP/%d.%d", major, minor)
+		request.ProtoMajor, request.ProtoMinor = major, minor
+		request.Close = major == 1 && minor == 0
 	}
 
 	for _, opt := range opts {
-		request, err = reqOptions(request, opt)
+		request, err = h.reqOptions(request, opt)
 		if err != nil {
 			return nil, err
 		}
 	}
 
+	for _, opt := range opts {
+		if key, ok := opt.(APIKey); ok && key.In == "query" {
+			param := h.encodeQuery(URL.Values{key.Key: {key.Value}})
+			if request.URL.RawQuery == "" {
+				request.URL.RawQuery = param
+			} else {
+				request.URL.RawQuery += "&" + param
+			}
+		}
+	}
+
+	if h.Compress && request.GetBody != nil && request.Header.Get("Content-Encoding") == "" {
+		compress := true
+		for _, opt := range opts {
+			if off, ok := opt.(NoCompress); ok && bool(off) {
+				compress = false
+			}
+			// compressing would read the whole file into memory
+			if _, ok := opt.(FileBody); ok {
+				compress = false
+			}
+		}
+		if compress {
+			if err := gzipRequestBody(request); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, opt := range opts {
+		if chunked, ok := opt.(ForceChunked); ok && bool(chunked) && request.Body != nil {
+			// GetBody keeps replays working, the length stays unknown
+			request.ContentLength = -1
+			request.TransferEncoding = []string{"chunked"}
+		}
+	}
+
+	if request.Header.Get("user-agent") == "" {
+		request.Header.Set("User-Agent", DefaultUA)
+	}
+
+	for _, opt := range opts {
+		if remove, ok := opt.(RemoveHeaders); ok {
+			for _, key := range remove {
+				request.Header.Del(key)
+				// an empty value stops net/http adding its own
+				if strings.EqualFold(key, "User-Agent") {
+					request.Header.Set("User-Agent", "")
+				}
+			}
+		}
+	}
+	return request, nil
+}
+
+// gzipBytes compress data
+func gzipBytes(data []byte) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	zw := gzip.NewWriter(buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// gzipRequestBody replace the replayable body with its gzip form
+func gzipRequestBody(request *http.Request) error {
+	body, err := request.GetBody()
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(body)
+	body.Close()
+	if err != nil {
+		return err
+	}
+	zipped, err := gzipBytes(data)
+	if err != nil {
+		return err
+	}
+	request.Body.Close()
+	request.Header.Set("Content-Encoding", "gzip")
+	request.ContentLength = int64(len(zipped))
+	request.Body = io.NopCloser(bytes.NewReader(zipped))
+	request.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(zipped)), nil
+	}
+	return nil
+}
+
+// RequestWithContext Universal client bound to ctx
+//
+// When an ExpectContentType check fails the body is closed and only the
+// *ContentTypeError is returned, with the start of the body in Snippet.
+func (h *HttpClient) RequestWithContext(ctx context.Context, method, url string, opts ...any) (*MiniResponse, error) {
+	release, err := h.acquireSlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	authGen := h.authGeneration()
+	request, err := h.buildRequest(ctx, method, url, opts...)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	res, err := h.send(request, opts)
+	if err != nil || res.Response.StatusCode != http.StatusUnauthorized {
+		return holdSlot(res, release), err
+	}
+
+	// refresh once and replay with the new credentials
+	refresher := h.authRefresher()
+	if refresher == nil || (request.Body != nil && request.GetBody == nil) {
+		return holdSlot(res, release), nil
+	}
+	res.Drain()
+	// the refresher may send through this client too
+	release()
+	if err := h.refreshAuth(ctx, refresher, authGen); err != nil {
+		return nil, err
+	}
+	request, err = h.buildRequest(ctx, method, url, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if release, err = h.acquireSlot(ctx); err != nil {
+		return nil, err
+	}
+	res, err = h.send(request, opts)
+	return holdSlot(res, release), err
+}
+
+// authGeneration count of completed auth refreshes
+func (h *HttpClient) authGeneration() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.authGen
+}
+
+// authRefresher the refresher set by SetAuthRefresher
+func (h *HttpClient) authRefresher() func(ctx context.Context) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.refresher
+}
+
+// refreshAuth run refresher unless another request refreshed since gen
+func (h *HttpClient) refreshAuth(ctx context.Context, refresher func(ctx context.Context) error, gen uint64) error {
+	h.refreshMu.Lock()
+	defer h.refreshMu.Unlock()
+	if h.authGeneration() != gen {
+		return nil
+	}
+	if err := refresher(ctx); err != nil {
+		return err
+	}
+	h.mu.Lock()
+	h.authGen++
+	h.mu.Unlock()
+	return nil
+}
+
+// Do Send a prepared request with the client's settings
+func (h *HttpClient) Do(request *http.Request) (*MiniResponse, error) {
+	if request.Header == nil {
+		request.Header = make(http.Header)
+	}
 	if request.Header.Get("user-agent") == "" {
 		request.Header.Set("User-Agent", DefaultUA)
 	}
+	release, err := h.acquireSlot(request.Context())
+	if err != nil {
+		return nil, err
+	}
+	res, err := h.send(request, nil)
+	return holdSlot(res, release), err
+}
 
+// send the request, opts are those it was built from
+func (h *HttpClient) send(request *http.Request, opts []any) (*MiniResponse, error) {
+	// requests from Do skipped buildRequest
+	if h.ForceHTTPS || h.RejectHTTP {
+		u := *request.URL
+		if err := h.secureURL(&u); err != nil {
+			return nil, err
+		}
+		if u != *request.URL {
+			original := request.URL.Host
+			request = request.WithContext(request.Context())
+			request.URL = &u
+			if request.Host == original {
+				request.Host = u.Host
+			}
+		}
+	}
 	// Make Client
-	cookieJar, err := cookiejar.New(nil)
+	cookieJar, err := h.cookieJar()
 	if err != nil {
 		return nil, err
 	}
 
-	if h.Timeout == 0 {
-		h.Timeout = 30
+	timeout := h.Timeout
+	if timeout == 0 {
+		timeout = 30
 	}
 
 	client := &http.Client{
 		Jar:     cookieJar,
-		Timeout: time.Duration(h.Timeout) * time.Second,
+		Timeout: time.Duration(timeout) * time.Second,
+	}
+	for _, opt := range opts {
+		if off, ok := opt.(NoCookies); ok && bool(off) {
+			client.Jar = nil
+		}
+	}
+	client.CheckRedirect = h.checkRedirect()
+	var hops []RedirectHop
+	if h.RecordRedirects {
+		client.CheckRedirect = recordRedirects(client.CheckRedirect, &hops)
+	}
+	if h.ForceHTTPS || h.RejectHTTP {
+		client.CheckRedirect = h.secureRedirects(client.CheckRedirect)
+	}
+	// surface proxy errors before sending
+	if _, err := h.getTransport(request.URL); err != nil {
+		return nil, err
+	}
+	client.Transport = transportFunc(h.roundTrip)
+	// Send Data
+	readTimeout, firstByteTimeout := h.ReadTimeout, h.FirstByteTimeout
+	if h.BodyReadRetries > 0 {
+		readTimeout, firstByteTimeout = 0, 0
+	}
+	var conn httptrace.GotConnInfo
+	request = traceConn(request, &conn)
+	cancel := context.CancelFunc(func() {})
+	if firstByteTimeout > 0 {
+		request, cancel = withCancel(request)
+	}
+	start := time.Now()
+	response, attempts, err := h.do(client, request)
+	elapsed := time.Since(start)
+	if err != nil {
+		cancel()
+		return nil, classifyError(err)
+	}
+	if readTimeout > 0 && conn.Conn != nil && response.ProtoMajor == 1 {
+		response.Body = &readTimeoutBody{body: response.Body, conn: conn.Conn, timeout: readTimeout}
+	}
+	if firstByteTimeout > 0 {
+		response.Body = newFirstByteBody(response.Body, cancel, firstByteTimeout)
+	}
+	miniRes := miniResPool.Get().(*MiniResponse)
+	miniRes.Request = request
+	response.Body = &quotaBody{ReadCloser: response.Body, h: h}
+	if transform := h.bodyTransform(); transform != nil {
+		response.Body = &transformBody{ReadCloser: response.Body, transform: transform}
+	}
+	miniRes.Response = response
+	miniRes.attempts = attempts
+	miniRes.elapsed = elapsed
+	miniRes.reused = conn.Reused
+	miniRes.unmarshal = h.jsonUnmarshaler()
+	miniRes.redirects = hops
+
+	for _, opt := range opts {
+		if expected, ok := opt.(ExpectContentType); ok {
+			if err := miniRes.checkContentType(string(expected), h.snippetLen()); err != nil {
+				miniRes.Close()
+				miniRes.Release()
+				return nil, err
+			}
+		}
+	}
+	return miniRes, nil
+}
+
+// RequestWithDeadline Universal client bounded by an absolute deadline
+//
+// The deadline covers the whole operation, replays and reading the body included.
+func (h *HttpClient) RequestWithDeadline(deadline time.Time, method, url string, opts ...any) (*MiniResponse, error) {
+	ctx, cancel := context.WithDeadline(h.baseContext(), deadline)
+	return h.requestWithCancel(ctx, cancel, method, url, opts...)
+}
+
+// requestWithCancel keep ctx alive until the body is closed, then cancel it
+func (h *HttpClient) requestWithCancel(ctx context.Context, cancel context.CancelFunc, method, url string, opts ...any) (*MiniResponse, error) {
+	res, err := h.RequestWithContext(ctx, method, url, opts...)
+	if res == nil {
+		cancel()
+		return nil, err
+	}
+	res.Response.Body = &cancelBody{ReadCloser: res.Response.Body, cancel: cancel}
+	return res, err
+}
+
+// cancelBody runs cancel once the body is closed, releasing a context or a slot
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelBody) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// quotaBody counts body bytes against the client quota
+type quotaBody struct {
+	io.ReadCloser
+	h *HttpClient
+}
+
+func (b *quotaBody) Read(p []byte) (int, error) {
+	if quota := b.h.quota.Load(); quota > 0 {
+		remaining := quota - b.h.downloaded.Load()
+		if remaining <= 0 {
+			return 0, ErrQuotaExceeded
+		}
+		if int64(len(p)) > remaining {
+			p = p[:remaining]
+		}
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.h.downloaded.Add(int64(n))
+	return n, err
+}
+
+// transformBody applies the body transform on first read
+type transformBody struct {
+	io.ReadCloser
+	transform func(io.Reader) (io.Reader, error)
+	reader    io.Reader
+	err       error
+}
+
+func (b *transformBody) Read(p []byte) (int, error) {
+	if b.reader == nil && b.err == nil {
+		b.reader, b.err = b.transform(b.ReadCloser)
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+	return b.reader.Read(p)
+}
+
+// checkRedirect redirect policy for the http.Client
+func (h *HttpClient) checkRedirect() func(req *http.Request, via []*http.Request) error {
+	if h.CheckRedirect != nil {
+		return h.CheckRedirect
 	}
-	clientTransport := new(http.Transport)
 	// disable redirect
 	if h.AutoRedirectDisable {
-		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return func(req *http.Request, via []*http.Request) error {
 			return http.ErrUseLastResponse
 		}
-	} else {
-		client.CheckRedirect = nil
 	}
-	// allow proxy
-	if h.Socks5Address != "" {
-		dialer, err := setProxy(h.Socks5Address)
-		if err != nil {
-			return nil, err
+	if !h.RedirectSameHostOnly && !h.RedirectDenyPrivateIPs && !h.ForwardAuthOnRedirect {
+		return nil
+	}
+
+	sameHostOnly := h.RedirectSameHostOnly
+	denyPrivateIPs := h.RedirectDenyPrivateIPs
+	forwardAuth := h.ForwardAuthOnRedirect
+	forwardAuthSameDomain := h.ForwardAuthSameDomain
+	return func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		host := req.URL.Hostname()
+		if sameHostOnly && !strings.EqualFold(host, via[0].URL.Hostname()) {
+			return fmt.Errorf("%w: cross host redirect to %s", ErrRedirectBlocked, host)
+		}
+		if denyPrivateIPs {
+			ips, err := net.DefaultResolver.LookupIPAddr(req.Context(), host)
+			if err != nil {
+				return err
+			}
+			for _, ip := range ips {
+				if isPrivateIP(ip.IP) {
+					return fmt.Errorf("%w: private address %s", ErrRedirectBlocked, ip.IP)
+				}
+			}
 		}
-		dialContext := func(ctx context.Context, network, address string) (net.Conn, error) {
-			return dialer.Dial(network, address)
+		if forwardAuth && req.Header.Get("Authorization") == "" {
+			auth := via[0].Header.Get("Authorization")
+			if auth != "" && sameSite(host, via[0].URL.Hostname(), forwardAuthSameDomain) {
+				req.Header.Set("Authorization", auth)
+			}
 		}
-		clientTransport.Proxy = nil
-		clientTransport.DialContext = dialContext
-		clientTransport.TLSHandshakeTimeout = time.Duration(30) * time.Second
+		return nil
 	}
-	if h.Insecure {
-		clientTransport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+}
+
+// sameSite compare hosts, or their registrable domains when sameDomain is set
+func sameSite(a, b string, sameDomain bool) bool {
+	if strings.EqualFold(a, b) {
+		return true
 	}
-	client.Transport = clientTransport
-	// Send Data
-	response, err := client.Do(request)
+	// IPs have no registrable domain, only an exact match counts
+	if !sameDomain || net.ParseIP(a) != nil || net.ParseIP(b) != nil {
+		return false
+	}
+	domainA, err := publicsuffix.EffectiveTLDPlusOne(strings.ToLower(a))
 	if err != nil {
-		return nil, err
+		return false
 	}
-	miniRes := new(MiniResponse)
-	miniRes.Request = request
-	miniRes.Response = response
-	return miniRes, nil
+	domainB, err := publicsuffix.EffectiveTLDPlusOne(strings.ToLower(b))
+	if err != nil {
+		return false
+	}
+	return domainA == domainB
+}
+
+// recordRedirects wrap check to append every followed hop to hops
+func recordRedirects(check func(req *http.Request, via []*http.Request) error, hops *[]RedirectHop) func(req *http.Request, via []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if check != nil {
+			if err := check(req, via); err != nil {
+				return err
+			}
+		} else if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		// a replayed request starts a new chain
+		if len(via) == 1 {
+			*hops = (*hops)[:0]
+		}
+		hop := RedirectHop{URL: via[len(via)-1].URL.String()}
+		if req.Response != nil {
+			hop.StatusCode = req.Response.StatusCode
+			hop.Location = req.Response.Header.Get("Location")
+		}
+		*hops = append(*hops, hop)
+		return nil
+	}
+}
+
+// secureRedirects apply secureURL to redirect targets before check
+func (h *HttpClient) secureRedirects(check func(req *http.Request, via []*http.Request) error) func(req *http.Request, via []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if err := h.secureURL(req.URL); err != nil {
+			return err
+		}
+		if check != nil {
+			return check(req, via)
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+}
+
+// isPrivateIP RFC1918, loopback, link-local and unspecified addresses
+func isPrivateIP(ip net.IP) bool {
+	return ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsUnspecified()
+}
+
+// do send the request, buffering the body when BodyReadRetries is set
+//
+// It also reports how many times the request was sent.
+func (h *HttpClient) do(client *http.Client, request *http.Request) (*http.Response, int, error) {
+	retries := h.BodyReadRetries
+	if retries <= 0 || (request.Body != nil && request.GetBody == nil) {
+		response, err := client.Do(request)
+		return response, 1, err
+	}
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && request.GetBody != nil {
+			body, err := request.GetBody()
+			if err != nil {
+				return nil, attempt, err
+			}
+			request.Body = body
+		}
+
+		response, err := client.Do(request)
+		if err != nil {
+			return nil, attempt + 1, err
+		}
+		data, err := io.ReadAll(response.Body)
+		response.Body.Close()
+		if err == nil {
+			response.Body = io.NopCloser(bytes.NewReader(data))
+			return response, attempt + 1, nil
+		}
+		if attempt >= retries || !isTransientReadError(err) {
+			return nil, attempt + 1, err
+		}
+	}
+}
+
+// isTransientReadError body read failures worth a replay
+func isTransientReadError(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		(errors.As(err, &netErr) && netErr.Timeout())
 }
 
 func (h *HttpClient) Get(url string, opts ...any) (*MiniResponse, error) {
-	h.Method = "GET"
-	return h.Request(url, opts...)
+	return h.RequestWithMethod("GET", url, opts...)
+}
+
+// GetJSON GET url and decode the JSON body into v, non-2xx is a *StatusError
+func (h *HttpClient) GetJSON(url string, v any, opts ...any) error {
+	res, err := h.Get(url, opts...)
+	return h.bindJSON(res, err, v)
+}
+
+// PostJSON POST body as JSON and decode the JSON response into out
+//
+// A nil out discards the response body. Non-2xx is a *StatusError.
+func (h *HttpClient) PostJSON(url string, body any, out any, opts ...any) error {
+	data, err := h.jsonMarshaler()(body)
+	if err != nil {
+		return err
+	}
+	opts = append([]any{BytesBody{Data: data, ContentType: "application/json"}}, opts...)
+	res, err := h.Post(url, opts...)
+	return h.bindJSON(res, err, out)
+}
+
+// bindJSON check the status of res and decode it into v, the body is always closed
+func (h *HttpClient) bindJSON(res *MiniResponse, err error, v any) error {
+	if err != nil {
+		if res != nil {
+			res.Close()
+		}
+		return err
+	}
+	if err := res.statusError(h.snippetLen()); err != nil {
+		return err
+	}
+	if v == nil {
+		res.Drain()
+		return nil
+	}
+	return res.BindJSON(v)
 }
 
 func (h *HttpClient) Post(url string, opts ...any) (*MiniResponse, error) {
-	h.Method = "POST"
-	return h.Request(url, opts...)
+	return h.RequestWithMethod("POST", url, opts...)
 }
 
 func (h *HttpClient) Put(url string, opts ...any) (*MiniResponse, error) {
-	h.Method = "PUT"
-	return h.Request(url, opts...)
+	return h.RequestWithMethod("PUT", url, opts...)
 }
 
 func (h *HttpClient) Patch(url string, opts ...any) (*MiniResponse, error) {
-	h.Method = "PATCH"
-	return h.Request(url, opts...)
+	return h.RequestWithMethod("PATCH", url, opts...)
 }
 
 func (h *HttpClient) Delete(url string, opts ...any) (*MiniResponse, error) {
-	h.Method = "DELETE"
-	return h.Request(url, opts...)
+	return h.RequestWithMethod("DELETE", url, opts...)
 }
 
 func (h *HttpClient) Connect(url string, opts ...any) (*MiniResponse, error) {
-	h.Method = "CONNECT"
-	return h.Request(url, opts...)
+	return h.RequestWithMethod("CONNECT", url, opts...)
 }
 
 func (h *HttpClient) Head(url string, opts ...any) (*MiniResponse, error) {
-	h.Method = "HEAD"
-	return h.Request(url, opts...)
+	return h.RequestWithMethod("HEAD", url, opts...)
+}
+
+// HeadContentLength Size url declares in a HEAD response, -1 when unknown
+func (h *HttpClient) HeadContentLength(url string, opts ...any) (int64, error) {
+	res, err := h.Head(url, opts...)
+	if err != nil {
+		return -1, err
+	}
+	res.Response.Body.Close()
+	return res.ContentLength(), nil
 }
 
 func (h *HttpClient) Options(url string, opts ...any) (*MiniResponse, error) {
-	h.Method = "OPTIONS"
-	return h.Request(url, opts...)
+	return h.RequestWithMethod("OPTIONS", url, opts...)
 }
 
 func (h *HttpClient) Trace(url string, opts ...any) (*MiniResponse, error) {
-	h.Method = "TRACE"
-	return h.Request(url, opts...)
+	return h.RequestWithMethod("TRACE", url, opts...)
 }
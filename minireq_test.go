@@ -1,9 +1,35 @@
 package minireq
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"testing"
+
+	"github.com/gorilla/websocket"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
 const HTTPBIN string = "https://httpbin.org/"
@@ -181,3 +207,2934 @@ func TestAnySet(t *testing.T) {
 		t.Log(statusCode)
 	}
 }
+
+func TestMaxConcurrentRequests(t *testing.T) {
+	var current, peak int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&current, 1)
+		defer atomic.AddInt32(&current, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	client.SetMaxConcurrentRequests(2)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := client.Get(server.URL)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			res.RawData()
+		}()
+	}
+	wg.Wait()
+
+	if peak > 2 {
+		t.Errorf("failed: %d in-flight requests", peak)
+	} else {
+		t.Log("succeed")
+	}
+}
+
+func TestRequestBuilder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"method":"`+r.Method+`","header":"`+r.Header.Get("X-A")+`","type":"`+r.Header.Get("Content-Type")+`"}`)
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	res, err := client.NewRequest().
+		Method("POST").
+		URL(server.URL).
+		JSON(JSONData{"foo": "bar"}).
+		Header("X-A", "b").
+		Timeout(5 * time.Second).
+		Send()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rawData, err := res.RawJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonData := rawData.(map[string]any)
+	if jsonData["method"] == "POST" && jsonData["header"] == "b" && jsonData["type"] == "application/json" {
+		t.Log("succeed")
+	} else {
+		t.Error("failed")
+	}
+}
+
+func TestGetPaginated(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("page")
+		switch {
+		case r.URL.Path == "/items":
+			http.Redirect(w, r, "/v2/items?"+r.URL.RawQuery, http.StatusFound)
+			return
+		case r.URL.Path == "/v2/items" && page == "1":
+			w.Header().Set("Link", `<items?page=2>; rel="next"`)
+		case r.URL.Path == "/v2/items":
+		case page == "1":
+			w.Header().Set("Link", `<`+server.URL+`/?page=2>; rel="next", <`+server.URL+`/?page=3>; rel="last"`)
+		case page == "2":
+			w.Header().Set("Link", `</?page=3>; rel="next"`)
+		}
+		io.WriteString(w, r.URL.Path+page)
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	collect := func(url string) string {
+		pages, err := client.GetPaginated(url, Params{"page": "1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for i := 0; pages.HasNext() && i < 5; i++ {
+			res, err := pages.Next()
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := res.RawData()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, string(data))
+		}
+		return strings.Join(got, ",")
+	}
+	plain := collect(server.URL)
+	redirected := collect(server.URL + "/items")
+	if plain == "/1,/2,/3" && redirected == "/v2/items1,/v2/items2" {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %s %s", plain, redirected)
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Query().Get("cursor") {
+		case "":
+			io.WriteString(w, `{"items":["a","b"],"next":"c1"}`)
+		case "c1":
+			io.WriteString(w, `{"items":["c"],"next":""}`)
+		}
+	}))
+	defer server.Close()
+
+	var items []string
+	client := NewClient()
+	err := client.Paginate(server.URL, func(res *MiniResponse) (string, bool, error) {
+		rawData, err := res.RawJSON()
+		if err != nil {
+			return "", false, err
+		}
+		jsonData := rawData.(map[string]any)
+		for _, item := range jsonData["items"].([]any) {
+			items = append(items, item.(string))
+		}
+		cursor := jsonData["next"].(string)
+		return "?cursor=" + cursor, cursor != "", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(items, ",") == "a,b,c" {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %v", items)
+	}
+}
+
+func TestPostBytes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+		io.Copy(w, r.Body)
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	res, err := client.Post(server.URL, BytesBody{Data: []byte{0x01, 0x02, 0x03}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := res.RawData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) == "\x01\x02\x03" && res.Response.Header.Get("Content-Type") == "application/octet-stream" {
+		t.Log("succeed")
+	} else {
+		t.Error("failed")
+	}
+}
+
+func TestErrorClass(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	client := NewClient()
+	_, err = client.Get("http://" + addr)
+	if !errors.Is(err, ErrConnectionRefused) || errors.Unwrap(err) == nil {
+		t.Errorf("failed: %v", err)
+	}
+
+	client.SetProxy(addr)
+	_, err = client.Get("http://example.com")
+	if !errors.Is(err, ErrProxyFailure) {
+		t.Errorf("failed: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(2 * time.Second)
+	}))
+	defer server.Close()
+
+	client = NewClient()
+	client.SetTimeout(1)
+	_, err = client.Get(server.URL)
+	if errors.Is(err, ErrTimeout) {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %v", err)
+	}
+}
+
+func TestH2C(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Proto)
+	})
+	server := httptest.NewServer(h2c.NewHandler(handler, &http2.Server{}))
+	defer server.Close()
+
+	client := NewClient()
+	client.SetH2C(true)
+	res, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := res.RawData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) == "HTTP/2.0" {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %s", data)
+	}
+}
+
+func TestBodyReadRetries(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			conn, buf, _ := w.(http.Hijacker).Hijack()
+			buf.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 10\r\n\r\nhello")
+			buf.Flush()
+			conn.Close()
+			return
+		}
+		io.WriteString(w, "helloworld")
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	client.SetBodyReadRetries(2)
+	res, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := res.RawData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) == "helloworld" && calls == 2 && res.Attempts() == 2 {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %s after %d calls", data, calls)
+	}
+}
+
+func TestEncodeSpaceAsPercent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.URL.RawQuery)
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	client.SetEncodeSpaceAsPercent(true)
+	res, err := client.Get(server.URL, Params{"q": "a b+c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := res.RawData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) == "q=a%20b%2Bc" {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %s", data)
+	}
+}
+
+func TestContextValue(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	type tenantKey struct{}
+	type traceKey struct{}
+	ctx := context.WithValue(context.Background(), traceKey{}, "trace-1")
+
+	client := NewClient()
+	res, err := client.RequestWithContext(ctx, "GET", server.URL, WithContextValue(tenantKey{}, "tenant-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Drain()
+
+	reqCtx := res.Request.Context()
+	if reqCtx.Value(tenantKey{}) == "tenant-1" && reqCtx.Value(traceKey{}) == "trace-1" {
+		t.Log("succeed")
+	} else {
+		t.Error("failed")
+	}
+}
+
+func TestRedirectHostPolicy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/local" {
+			http.Redirect(w, r, "http://127.0.0.1:1/", http.StatusFound)
+			return
+		}
+		http.Redirect(w, r, "http://localhost.invalid/", http.StatusFound)
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	client.SetRedirectHostPolicy(false, false)
+	_, err := client.Get(server.URL + "/cross")
+	if !errors.Is(err, ErrRedirectBlocked) {
+		t.Errorf("failed: %v", err)
+	}
+
+	client.SetRedirectHostPolicy(true, true)
+	_, err = client.Get(server.URL + "/local")
+	if errors.Is(err, ErrRedirectBlocked) {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %v", err)
+	}
+}
+
+func TestBuildRequest(t *testing.T) {
+	client := NewClient()
+	req, err := client.BuildRequest("POST", "https://example.com/post", Params{"foo": "bar"}, JSONData{"foo": "bar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok1 := req.Method == "POST" && req.URL.String() == "https://example.com/post?foo=bar"
+	ok2 := req.Header.Get("Content-Type") == "application/json" && req.Header.Get("User-Agent") == DefaultUA
+	ok3 := string(body) == `{"foo":"bar"}`
+	if ok1 && ok2 && ok3 {
+		t.Log("succeed")
+	} else {
+		t.Error("failed")
+	}
+}
+
+func TestRegisterEncoder(t *testing.T) {
+	type CSVBody [][]string
+
+	client := NewClient()
+	client.RegisterEncoder(CSVBody{}, func(request *http.Request, opt any) error {
+		var buf bytes.Buffer
+		if err := csv.NewWriter(&buf).WriteAll(opt.(CSVBody)); err != nil {
+			return err
+		}
+		request.Header.Set("Content-Type", "text/csv")
+		request.ContentLength = int64(buf.Len())
+		request.Body = io.NopCloser(&buf)
+		return nil
+	})
+
+	req, err := client.BuildRequest("POST", "https://example.com/", CSVBody{{"a", "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(req.Body)
+	if string(body) == "a,b\n" && req.Header.Get("Content-Type") == "text/csv" {
+		t.Log("succeed")
+	} else {
+		t.Error("failed")
+	}
+}
+
+func TestWebSocket(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.WriteMessage(websocket.TextMessage, []byte(r.Header.Get("X-Token")))
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	conn, res, err := client.WebSocket("ws"+strings.TrimPrefix(server.URL, "http"), Headers{"X-Token": "abc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	_, msg, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(msg) == "abc" && res.Response.StatusCode == http.StatusSwitchingProtocols {
+		t.Log("succeed")
+	} else {
+		t.Error("failed")
+	}
+}
+
+func TestPerHostTransport(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	server1 := httptest.NewServer(handler)
+	defer server1.Close()
+	server2 := httptest.NewServer(handler)
+	defer server2.Close()
+
+	client := NewClient()
+	client.SetPerHostTransport(true)
+	for _, u := range []string{server1.URL, server2.URL, server1.URL} {
+		res, err := client.Get(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Drain()
+	}
+
+	if len(client.transports) == 2 {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %d transports", len(client.transports))
+	}
+}
+
+func TestMultipartReader(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mw := multipart.NewWriter(w)
+		w.Header().Set("Content-Type", "multipart/mixed; boundary="+mw.Boundary())
+		for _, v := range []string{"a", "b"} {
+			part, _ := mw.CreatePart(nil)
+			io.WriteString(part, v)
+		}
+		mw.Close()
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	res, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mr, err := res.MultipartReader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parts []string
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(part)
+		parts = append(parts, string(data))
+	}
+	if strings.Join(parts, ",") == "a,b" {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %v", parts)
+	}
+}
+
+func TestSetDefaults(t *testing.T) {
+	SetDefaults(func(h *HttpClient) {
+		h.SetTimeout(5)
+		h.SetInsecure(true)
+	})
+	defer SetDefaults(nil)
+
+	client := NewClient()
+	client.SetTimeout(10)
+	if client.Timeout == 10 && client.Insecure {
+		t.Log("succeed")
+	} else {
+		t.Error("failed")
+	}
+}
+
+func TestWarmup(t *testing.T) {
+	var conns int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	client := NewClient()
+	if err := client.Warmup(context.Background(), server.URL, 5); err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := client.Get(server.URL)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			res.Drain()
+		}()
+	}
+	wg.Wait()
+	if atomic.LoadInt32(&conns) == 5 {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %d connections", conns)
+	}
+}
+
+func TestProxyFunc(t *testing.T) {
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "proxied "+r.URL.String())
+	}))
+	defer proxyServer.Close()
+	proxyURL, _ := url.Parse(proxyServer.URL)
+
+	client := NewClient()
+	client.SetProxyFunc(func(r *http.Request) (*url.URL, error) {
+		if r.URL.Host == "example.com" {
+			return proxyURL, nil
+		}
+		return nil, nil
+	})
+	res, err := client.Get("http://example.com/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := res.RawData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) == "proxied http://example.com/foo" {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %s", data)
+	}
+}
+
+func TestExpectContentType(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, "<html>error</html>")
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	client.SetErrorBodySnippetLen(6)
+	res, err := client.Get(server.URL, ExpectContentType("application/json"))
+	var ctErr *ContentTypeError
+	if errors.As(err, &ctErr) && ctErr.Snippet == "<html>" && res == nil {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %v", err)
+	}
+}
+
+func TestChannelBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(w, r.Body)
+	}))
+	defer server.Close()
+
+	chunks := make(chan []byte)
+	go func() {
+		defer close(chunks)
+		for _, c := range []string{"foo", "", "bar"} {
+			chunks <- []byte(c)
+		}
+	}()
+
+	client := NewClient()
+	client.SetBodyReadRetries(2)
+	res, err := client.Post(server.URL, ChannelBody{Chunks: chunks, ContentType: "text/plain"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := res.RawData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) == "foobar" {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %s", data)
+	}
+}
+
+func TestRequestWithDeadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	_, err := client.RequestWithDeadline(time.Now().Add(200*time.Millisecond), "GET", server.URL)
+	if errors.Is(err, ErrTimeout) {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %v", err)
+	}
+}
+
+func TestSubmitForm(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "s1"})
+			io.WriteString(w, `<html><body>
+<form id="search" action="/search"><input name="q"></form>
+<form id="login" action="/login" method="post">
+  <input type="hidden" name="csrf" value="token123">
+  <input name="user" value="">
+  <input type="password" name="pass">
+  <input type="submit" name="go" value="Login">
+</form></body></html>`)
+			return
+		}
+		session, _ := r.Cookie("session")
+		r.ParseForm()
+		io.WriteString(w, r.URL.Path+" "+session.Value+" "+r.PostForm.Encode())
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	res, err := client.SubmitForm(server.URL, "#login", FormKV{"user": "foo", "pass": "bar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := res.RawData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) == "/login s1 csrf=token123&pass=bar&user=foo" {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %s", data)
+	}
+}
+
+func TestForwardAuthOnRedirect(t *testing.T) {
+	client := NewClient()
+	client.SetForwardAuthOnRedirect(true)
+	client.SetForwardAuthSameDomain(true)
+	check := client.checkRedirect()
+
+	origin, _ := http.NewRequest("GET", "https://api.example.com/login", nil)
+	origin.Header.Set("Authorization", "Bearer token")
+
+	same, _ := http.NewRequest("GET", "https://example.com/home", nil)
+	other, _ := http.NewRequest("GET", "https://example.org/home", nil)
+	if err := check(same, []*http.Request{origin}); err != nil {
+		t.Fatal(err)
+	}
+	if err := check(other, []*http.Request{origin}); err != nil {
+		t.Fatal(err)
+	}
+	if same.Header.Get("Authorization") == "Bearer token" && other.Header.Get("Authorization") == "" {
+		t.Log("succeed")
+	} else {
+		t.Error("failed")
+	}
+}
+
+func TestFromCurl(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		body, _ := io.ReadAll(r.Body)
+		io.WriteString(w, r.Method+" "+r.Header.Get("X-A")+" "+user+":"+pass+" "+string(body))
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	req, err := client.FromCurl(`curl '` + server.URL + `' \
+  -X PUT -H 'X-A: b c' -u "foo:bar" --data-raw '{"k":"v"}' --compressed`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := res.RawData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `PUT b c foo:bar {"k":"v"}` {
+		t.Errorf("failed: %s", data)
+	}
+
+	if _, err := client.FromCurl("curl -F a=b " + server.URL); err != nil {
+		t.Log("succeed")
+	} else {
+		t.Error("failed")
+	}
+}
+
+func TestHeadContentLength(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "1234")
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	size, err := client.HeadContentLength(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size == 1234 {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %d", size)
+	}
+}
+
+type mockTransport struct {
+	requests []*http.Request
+}
+
+func (m *mockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	m.requests = append(m.requests, req)
+	res := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     make(http.Header),
+		Body:       io.NopCloser(strings.NewReader(req.URL.Query().Get("foo"))),
+		Request:    req,
+	}
+	if req.URL.Path == "/old" {
+		res.StatusCode = http.StatusFound
+		res.Header.Set("Location", "/new?foo=baz")
+	}
+	return res, nil
+}
+
+func TestSetTransport(t *testing.T) {
+	mock := new(mockTransport)
+	client := NewClient()
+	client.SetTransport(mock)
+
+	res, err := client.Get("http://mock.local/old", Params{"foo": "bar"}, Headers{"X-A": "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := res.RawData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok1 := string(data) == "baz" && len(mock.requests) == 2
+	ok2 := mock.requests[0].URL.RawQuery == "foo=bar" && mock.requests[0].Header.Get("X-A") == "b"
+	if ok1 && ok2 {
+		t.Log("succeed")
+	} else {
+		t.Error("failed")
+	}
+}
+
+func TestHeaderOrder(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	lines := make(chan []string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		var got []string
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil || line == "\r\n" {
+				break
+			}
+			name, _, _ := strings.Cut(line, ":")
+			got = append(got, name)
+		}
+		io.WriteString(conn, "HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nok")
+		lines <- got
+	}()
+
+	client := NewClient()
+	client.SetHeaderOrder([]string{"Host", "user-agent", "Accept", "X-B"})
+	res, err := client.Get("http://"+listener.Addr().String()+"/", Headers{"X-B": "1", "Accept": "*/*", "X-A": "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := res.RawData()
+
+	got := strings.Join((<-lines)[1:], ",")
+	if string(data) == "ok" && got == "Host,user-agent,Accept,X-B,Connection,X-A" {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %s", got)
+	}
+}
+
+func TestFrameReader(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, frame := range []string{"foo", "", "barbaz"} {
+			binary.Write(w, binary.BigEndian, uint32(len(frame)))
+			io.WriteString(w, frame)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	res, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frames := res.FrameReader(Uint32BigEndian)
+	defer frames.Close()
+
+	var got []string
+	for {
+		frame, err := frames.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, string(frame))
+	}
+	if strings.Join(got, ",") == "foo,,barbaz" {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %v", got)
+	}
+}
+
+func TestAPIKey(t *testing.T) {
+	client := NewClient()
+	req, err := client.BuildRequest("GET", "https://example.com/?a=1",
+		APIKey{Key: "X-Api-Key", Value: "k1"},
+		APIKey{Key: "api_key", Value: "k2", In: "query"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Header.Get("X-Api-Key") == "k1" && req.URL.RawQuery == "a=1&api_key=k2" {
+		t.Log("succeed")
+	} else {
+		t.Error("failed")
+	}
+}
+
+func TestForceChunked(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if atomic.AddInt32(&calls, 1) == 1 {
+			conn, buf, _ := w.(http.Hijacker).Hijack()
+			buf.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 10\r\n\r\n")
+			buf.Flush()
+			conn.Close()
+			return
+		}
+		io.WriteString(w, strings.Join(r.TransferEncoding, ",")+" "+string(body))
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	client.SetBodyReadRetries(1)
+	res, err := client.Post(server.URL, ForceChunked(true), JSONData{"foo": "bar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := res.RawData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) == `chunked {"foo":"bar"}` {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %s", data)
+	}
+}
+
+func TestSetCookie(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "s2"})
+		}
+		var names []string
+		for _, c := range r.Cookies() {
+			names = append(names, c.Name+"="+c.Value)
+		}
+		io.WriteString(w, strings.Join(names, ","))
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	u, _ := url.Parse(server.URL)
+	client.SetCookieValue(u, "token", "t1")
+
+	res, err := client.Get(server.URL + "/login")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Drain()
+	res, err = client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := res.RawData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) == "token=t1,session=s2" {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %s", data)
+	}
+}
+
+func TestRedirectChain(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a":
+			http.Redirect(w, r, "/b", http.StatusMovedPermanently)
+		case "/b":
+			http.Redirect(w, r, "/c", http.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	client.SetRecordRedirects(true)
+	res, err := client.Get(server.URL + "/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Drain()
+
+	chain := res.RedirectChain()
+	if len(chain) == 2 && chain[0].URL == server.URL+"/a" && chain[0].StatusCode == 301 && chain[1].Location == "/c" {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %+v", chain)
+	}
+}
+
+func TestResponseError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ok" {
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, `{"error":{"code":1,"message":"no such user"}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	res, err := client.Get(server.URL + "/ok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Error() != nil {
+		t.Error("failed")
+	}
+
+	res, err = client.Get(server.URL + "/user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resErr := res.Error()
+	var statusErr *StatusError
+	if !errors.As(resErr, &statusErr) || statusErr.StatusCode != 404 || resErr.Error() != "404 Not Found: no such user" {
+		t.Fatalf("failed: %v", resErr)
+	}
+	data, _ := res.RawData()
+	if len(data) > 0 {
+		t.Log("succeed")
+	} else {
+		t.Error("failed")
+	}
+}
+
+func TestAuthRefresher(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer new" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	var refreshes int32
+	client := NewClient()
+	client.SetHeader("Authorization", "Bearer old")
+	client.SetAuthRefresher(func(ctx context.Context) error {
+		atomic.AddInt32(&refreshes, 1)
+		time.Sleep(20 * time.Millisecond)
+		client.SetHeader("Authorization", "Bearer new")
+		return nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := client.Post(server.URL, JSONData{"foo": "bar"})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			data, _ := res.RawData()
+			if res.Response.StatusCode != 200 || string(data) != `{"foo":"bar"}` {
+				t.Errorf("failed: %d %s", res.Response.StatusCode, data)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if refreshes == 1 {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %d refreshes", refreshes)
+	}
+}
+
+func TestRemoveHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, hasUA := r.Header["User-Agent"]
+		fmt.Fprintf(w, "%q %v", r.Header.Get("Authorization"), hasUA)
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	client.SetHeader("Authorization", "Bearer token")
+	res, err := client.Get(server.URL, RemoveHeaders{"authorization", "User-Agent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := res.RawData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) == `"" false` {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %s", data)
+	}
+}
+
+func TestTransferCookiesTo(t *testing.T) {
+	u, _ := url.Parse("https://example.com/")
+	auth := NewClient()
+	auth.SetCookieValue(u, "session", "s1")
+
+	worker := NewClient()
+	if err := auth.TransferCookiesTo(worker, u); err != nil {
+		t.Fatal(err)
+	}
+	jar, _ := worker.CookieJar()
+	cookies := jar.Cookies(u)
+	if len(cookies) == 1 && cookies[0].Value == "s1" {
+		t.Log("succeed")
+	} else {
+		t.Error("failed")
+	}
+}
+
+func TestNoDecompress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		io.WriteString(gz, "hello")
+		gz.Close()
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	res, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, _ := res.RawData()
+
+	res, err = client.Get(server.URL, NoDecompress(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := res.RawData()
+	gz, err := gzip.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	unpacked, _ := io.ReadAll(gz)
+	if string(plain) == "hello" && string(unpacked) == "hello" {
+		t.Log("succeed")
+	} else {
+		t.Error("failed")
+	}
+}
+
+func TestResponseBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "hello")
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	res, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := res.Body()
+	data, _ := io.ReadAll(body)
+	body.Close()
+	_, err = res.RawData()
+	if string(data) == "hello" && errors.Is(err, ErrBodyTaken) && res.Close() == nil && !res.Drain() {
+		t.Log("succeed")
+	} else {
+		t.Error("failed")
+	}
+}
+
+func TestProxyConnectHeader(t *testing.T) {
+	var token string
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token = r.Header.Get("X-Proxy-Token")
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer proxyServer.Close()
+	proxyURL, _ := url.Parse(proxyServer.URL)
+
+	client := NewClient()
+	client.SetProxyFunc(http.ProxyURL(proxyURL))
+	client.SetProxyConnectHeader(http.Header{"X-Proxy-Token": {"abc"}})
+	_, err := client.Get("https://example.com/")
+	if err != nil && token == "abc" {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %v %q", err, token)
+	}
+}
+
+func TestDownloadQuota(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "0123456789")
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	client.SetDownloadQuota(15)
+	res, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _ := res.RawData()
+	res, err = client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = res.RawData()
+	if len(first) == 10 && errors.Is(err, ErrQuotaExceeded) && client.BytesDownloaded() == 15 {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %v %d", err, client.BytesDownloaded())
+	}
+}
+
+func TestIdleConnTimeout(t *testing.T) {
+	u, _ := url.Parse("http://example.com")
+	client := NewClient()
+	transport, _ := client.getTransport(u)
+	defaulted := transport.(*http.Transport).IdleConnTimeout
+
+	client.SetIdleConnTimeout(0)
+	transport, _ = client.getTransport(u)
+	unlimited := transport.(*http.Transport).IdleConnTimeout
+	if defaulted == 90*time.Second && unlimited == 0 {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %v %v", defaulted, unlimited)
+	}
+}
+
+func BenchmarkJSONData(b *testing.B) {
+	data := JSONData{}
+	for i := 0; i < 1000; i++ {
+		data[fmt.Sprintf("key%d", i)] = strings.Repeat("v", 64)
+	}
+	client := NewClient()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := client.BuildRequest("POST", "http://example.com", data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkFormData(b *testing.B) {
+	file := filepath.Join(b.TempDir(), "upload.txt")
+	if err := os.WriteFile(file, bytes.Repeat([]byte("x"), 64<<10), 0o644); err != nil {
+		b.Fatal(err)
+	}
+	data := FormData{
+		Values: map[string]string{"name": "minireq"},
+		Files:  map[string]string{"file": file},
+	}
+	client := NewClient()
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := client.BuildRequest("POST", "http://example.com", data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestRelease(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.URL.Path)
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	var got []string
+	for _, path := range []string{"/a", "/b"} {
+		res, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := res.RawData()
+		got = append(got, string(data))
+		res.Close()
+		res.Release()
+	}
+	if got[0] == "/a" && got[1] == "/b" {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %v", got)
+	}
+}
+
+func TestReadTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "start")
+		w.(http.Flusher).Flush()
+		if r.URL.Path == "/stall" {
+			<-release
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := NewClient()
+	client.SetReadTimeout(100 * time.Millisecond)
+	res, err := client.Get(server.URL + "/ok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, okErr := res.RawData()
+
+	res, err = client.Get(server.URL + "/stall")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = res.RawData()
+	if string(data) == "start" && okErr == nil && errors.Is(err, ErrReadTimeout) {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %v %v", okErr, err)
+	}
+}
+
+func TestFirstByteTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		if r.URL.Path == "/stall" {
+			<-release
+		}
+		io.WriteString(w, "body")
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := NewClient()
+	client.SetFirstByteTimeout(100 * time.Millisecond)
+	res, err := client.Get(server.URL + "/ok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, okErr := res.RawData()
+
+	res, err = client.Get(server.URL + "/stall")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = res.RawData()
+	if string(data) == "body" && okErr == nil && errors.Is(err, ErrTimeout) {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %v %v", okErr, err)
+	}
+}
+
+func TestFormDataGzip(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") != "gzip" {
+			http.Error(w, "not gzip", http.StatusBadRequest)
+			return
+		}
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		r.Body = gz
+		r.Header.Del("Content-Encoding")
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		io.WriteString(w, r.FormValue("name"))
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	res, err := client.Post(server.URL, FormData{Values: map[string]string{"name": "minireq"}, Gzip: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := res.RawData()
+	if string(data) == "minireq" {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %s", data)
+	}
+}
+
+func TestCancelAll(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			close(started)
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		}
+		io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := NewClient()
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.Get(server.URL + "/slow")
+		done <- err
+	}()
+	<-started
+	client.CancelAll()
+	err := <-done
+
+	res, nextErr := client.Get(server.URL)
+	if nextErr != nil {
+		t.Fatal(nextErr)
+	}
+	data, _ := res.RawData()
+	if errors.Is(err, context.Canceled) && string(data) == "ok" {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %v", err)
+	}
+}
+
+func TestCSVReader(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/csv; charset=iso-8859-1")
+		w.Write([]byte("name,city\nana,caf\xe9\n"))
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	res, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := res.CSVReader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := reader.ReadAll()
+	if err == nil && len(rows) == 2 && rows[1][1] == "café" {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %v %v", rows, err)
+	}
+}
+
+func TestEnableCompression(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := io.Reader(r.Body)
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			body = gz
+		}
+		data, _ := io.ReadAll(body)
+		fmt.Fprintf(w, "%s %s", r.Header.Get("Content-Encoding"), data)
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	client.EnableCompression()
+	res, err := client.Post(server.URL, JSONData{"a": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	zipped, _ := res.RawData()
+	res, err = client.Post(server.URL, JSONData{"a": 1}, NoCompress(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, _ := res.RawData()
+	if string(zipped) == `gzip {"a":1}` && string(plain) == ` {"a":1}` {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %s | %s", zipped, plain)
+	}
+}
+
+func TestSetProtocolVersion(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %v", r.Proto, r.Close)
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	client := NewClient()
+	client.SetInsecure(true)
+	var got []string
+	for _, v := range [][2]int{{2, 0}, {1, 1}, {1, 0}} {
+		if err := client.SetProtocolVersion(v[0], v[1]); err != nil {
+			t.Fatal(err)
+		}
+		res, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := res.RawData()
+		got = append(got, string(data))
+	}
+	invalid := client.SetProtocolVersion(3, 0)
+	if got[0] == "HTTP/2.0 false" && got[1] == "HTTP/1.1 false" && got[2] == "HTTP/1.1 true" && invalid != nil {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %v", got)
+	}
+}
+
+func TestProxyPool(t *testing.T) {
+	newProxy := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, name)
+		}))
+	}
+	proxyA, proxyB := newProxy("a"), newProxy("b")
+	defer proxyA.Close()
+	defer proxyB.Close()
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+
+	fetch := func(client *HttpClient) string {
+		res, err := client.Get("http://example.com/")
+		if err != nil {
+			return err.Error()
+		}
+		data, _ := res.RawData()
+		return string(data)
+	}
+
+	client := NewClient()
+	if err := client.SetProxyPool([]string{proxyA.URL, proxyB.URL}, ProxyRoundRobin); err != nil {
+		t.Fatal(err)
+	}
+	rotated := fetch(client) + fetch(client) + fetch(client)
+
+	if err := client.SetProxyPool([]string{dead.URL, proxyB.URL}, ProxyFailover); err != nil {
+		t.Fatal(err)
+	}
+	failover := fetch(client) + fetch(client)
+	if rotated == "aba" && failover == "bb" {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %s %s", rotated, failover)
+	}
+}
+
+func TestNewClientWithTransport(t *testing.T) {
+	var conns atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	server.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	shared := &http.Transport{}
+	defer shared.CloseIdleConnections()
+	for _, client := range []*HttpClient{NewClientWithTransport(shared), NewClientWithTransport(shared)} {
+		res, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.RawData()
+	}
+	if conns.Load() == 1 {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %d connections", conns.Load())
+	}
+}
+
+func TestFailedRequestsReuseConnection(t *testing.T) {
+	var conns atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://example.com/", http.StatusFound)
+	}))
+	server.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	client := NewClient()
+	client.SetRedirectHostPolicy(false, false)
+	failed := 0
+	for i := 0; i < 10; i++ {
+		if _, err := client.Get(server.URL); errors.Is(err, ErrRedirectBlocked) {
+			failed++
+		}
+	}
+	if failed == 10 && conns.Load() == 1 {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %d errors, %d connections", failed, conns.Load())
+	}
+}
+
+func TestGetJSON(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.Error(w, "no such user", http.StatusNotFound)
+			return
+		}
+		io.WriteString(w, `{"name":"minireq"}`)
+	}))
+	defer server.Close()
+
+	var user struct {
+		Name string `json:"name"`
+	}
+	client := NewClient()
+	err := client.GetJSON(server.URL, &user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var statusErr *StatusError
+	err = client.GetJSON(server.URL+"/missing", &user)
+	if user.Name == "minireq" && errors.As(err, &statusErr) && statusErr.StatusCode == 404 && statusErr.Snippet == "no such user\n" {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %v", err)
+	}
+}
+
+func TestPostJSONHelper(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			http.Error(w, "bad content type", http.StatusUnsupportedMediaType)
+			return
+		}
+		var in struct{ A int }
+		json.NewDecoder(r.Body).Decode(&in)
+		fmt.Fprintf(w, `{"b":%d}`, in.A*2)
+	}))
+	defer server.Close()
+
+	var out struct{ B int }
+	client := NewClient()
+	err := client.PostJSON(server.URL, struct{ A int }{21}, &out)
+	fired := client.PostJSON(server.URL, []int{1}, nil)
+	if err == nil && fired == nil && out.B == 42 {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %v %v %d", err, fired, out.B)
+	}
+}
+
+func TestRequestBody(t *testing.T) {
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		received = string(data)
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	request, err := client.BuildRequest("POST", server.URL, FormKV{"a": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := RequestBody(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := client.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Close()
+	empty, _ := RequestBody(httptest.NewRequest("GET", "/", nil))
+	if string(body) == "a=1" && received == "a=1" && empty == nil {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %q %q", body, received)
+	}
+}
+
+func TestURLNormalization(t *testing.T) {
+	client := NewClient()
+	client.SetURLNormalization(true)
+	request, _ := client.BuildRequest("GET", "http://example.com//a///b/?q=1")
+	collapsed := request.URL.String()
+
+	client.SetTrailingSlash("strip")
+	request, _ = client.BuildRequest("GET", "http://example.com//a///b/")
+	stripped := request.URL.String()
+
+	client.SetTrailingSlash("add")
+	request, _ = client.BuildRequest("GET", "http://example.com/a")
+	added := request.URL.String()
+	if collapsed == "http://example.com/a/b/?q=1" && stripped == "http://example.com/a/b" && added == "http://example.com/a/" && client.SetTrailingSlash("x") != nil {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %s %s %s", collapsed, stripped, added)
+	}
+}
+
+func TestNoCookies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/set" {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: r.URL.Query().Get("v")})
+		}
+		if c, err := r.Cookie("session"); err == nil {
+			io.WriteString(w, c.Value)
+		}
+	}))
+	defer server.Close()
+
+	fetch := func(client *HttpClient, path string, opts ...any) string {
+		res, err := client.Get(server.URL+path, opts...)
+		if err != nil {
+			return err.Error()
+		}
+		data, _ := res.RawData()
+		return string(data)
+	}
+
+	client := NewClient()
+	fetch(client, "/set?v=s1")
+	fetch(client, "/set?v=s2", NoCookies(true))
+	skipped := fetch(client, "/", NoCookies(true))
+	kept := fetch(client, "/")
+	if skipped == "" && kept == "s1" {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %q %q", skipped, kept)
+	}
+}
+
+func TestPreEncodedForm(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s %s", r.Header.Get("Content-Type"), data)
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	res, err := client.Post(server.URL, PreEncodedForm("b=2&a=%7e1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := res.RawData()
+	if string(data) == "application/x-www-form-urlencoded b=2&a=%7e1" {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %s", data)
+	}
+}
+
+func TestElapsed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	res, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Close()
+	if res.Elapsed() >= 50*time.Millisecond {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %v", res.Elapsed())
+	}
+}
+
+func TestHostOverride(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s", r.Host, r.TLS.ServerName)
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	client.SetInsecure(true)
+	client.SetTLSServerName("canary.example.com")
+	res, err := client.Get(server.URL, Host("canary.example.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := res.RawData()
+	if string(data) == "canary.example.com canary.example.com" {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %s", data)
+	}
+}
+
+func TestFileBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s %d %s", r.Method, r.ContentLength, data)
+	}))
+	defer server.Close()
+
+	file := filepath.Join(t.TempDir(), "upload.txt")
+	if err := os.WriteFile(file, []byte("large file"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	client := NewClient()
+	res, err := client.Put(server.URL, FileBody{Path: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := res.RawData()
+	if string(data) == "PUT 10 large file" {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %s", data)
+	}
+}
+
+func TestFileBodyReplay(t *testing.T) {
+	var mu sync.Mutex
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received = append(received, string(data))
+		first := len(received) == 1
+		mu.Unlock()
+		if first {
+			conn, buf, _ := w.(http.Hijacker).Hijack()
+			buf.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 10\r\n\r\nok")
+			buf.Flush()
+			conn.Close()
+			return
+		}
+		io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	file := filepath.Join(t.TempDir(), "upload.txt")
+	if err := os.WriteFile(file, []byte("large file"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	client := NewClient()
+	client.SetBodyReadRetries(1)
+	res, err := client.Put(server.URL, FileBody{Path: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Close()
+	if len(received) == 2 && received[0] == "large file" && received[1] == "large file" && res.Attempts() == 2 {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %q", received)
+	}
+}
+
+func TestCustomMethods(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s %s %s", r.Method, r.Header.Get("Depth"), data)
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	client.SetBodyReadRetries(1)
+	var got []string
+	for _, method := range []string{"PROPFIND", "REPORT", "MKCOL"} {
+		res, err := client.RequestWithMethod(method, server.URL, Headers{"Depth": "1"}, BytesBody{Data: []byte("<propfind/>"), ContentType: "application/xml"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := res.RawData()
+		got = append(got, string(data))
+	}
+	if got[0] == "PROPFIND 1 <propfind/>" && got[1] == "REPORT 1 <propfind/>" && got[2] == "MKCOL 1 <propfind/>" {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %q", got)
+	}
+}
+
+func TestPathParams(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.URL.EscapedPath())
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	res, err := client.Get(server.URL+"/users/{id}/posts/{postId}", PathParams{"id": "a/../b", "postId": "7"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := res.RawData()
+	if string(data) == "/users/a%2F..%2Fb/posts/7" {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %s", data)
+	}
+}
+
+func TestSetTransportConfig(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			client.SetTransportConfig(func(cfg *TransportConfigBuilder) {
+				cfg.IdleConnTimeout = 30 + i
+				cfg.ProtoMajor, cfg.ProtoMinor = 1, 1
+			})
+			if res, err := client.Get(server.URL); err == nil {
+				res.Drain()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	cfg := client.Config()
+	if cfg.ProtoMajor == 1 && cfg.ProtoMinor == 1 && cfg.IdleConnTimeout >= 30*time.Second {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %+v", cfg)
+	}
+}
+
+func TestResponseBodyTransform(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, base64.StdEncoding.EncodeToString([]byte("secret")))
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	client.SetResponseBodyTransform(func(r io.Reader) (io.Reader, error) {
+		return base64.NewDecoder(base64.StdEncoding, r), nil
+	})
+	res, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := res.RawData()
+	if string(data) == "secret" {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %s", data)
+	}
+}
+
+func TestCheckProxy(t *testing.T) {
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Host == "blocked.example.com" {
+			http.Error(w, "denied", http.StatusForbidden)
+		}
+	}))
+	defer proxyServer.Close()
+	proxyURL, _ := url.Parse(proxyServer.URL)
+
+	client := NewClient()
+	unset := client.CheckProxy(context.Background(), "http://example.com/")
+	client.SetProxyFunc(http.ProxyURL(proxyURL))
+	ok := client.CheckProxy(context.Background(), "http://example.com/")
+	blocked := client.CheckProxy(context.Background(), "http://blocked.example.com/")
+	if unset != nil && ok == nil && errors.Is(blocked, ErrProxyFailure) {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %v %v %v", unset, ok, blocked)
+	}
+}
+
+func TestSetAcceptLanguage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Header.Get("Accept-Language"))
+	}))
+	defer server.Close()
+
+	fetch := func(client *HttpClient, opts ...any) string {
+		res, err := client.Get(server.URL, opts...)
+		if err != nil {
+			return err.Error()
+		}
+		data, _ := res.RawData()
+		return string(data)
+	}
+
+	client := NewClient()
+	client.SetAcceptLanguage("en-US", "en", "fr")
+	weighted := fetch(client)
+	overridden := fetch(client, Headers{"Accept-Language": "de"})
+	client.SetAcceptLanguage()
+	removed := fetch(client)
+	if weighted == "en-US,en;q=0.9,fr;q=0.8" && overridden == "de" && removed == "" {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %q %q %q", weighted, overridden, removed)
+	}
+}
+
+func TestRedirectLocation(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a/relative":
+			w.Header().Set("Location", "../login")
+			w.WriteHeader(http.StatusFound)
+		case "/absolute":
+			w.Header().Set("Location", server.URL+"/login")
+			w.WriteHeader(http.StatusFound)
+		default:
+			io.WriteString(w, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	var followed, kept []string
+	client := NewClient()
+	for _, path := range []string{"/a/relative", "/absolute"} {
+		res, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := res.RawData()
+		followed = append(followed, string(data))
+	}
+
+	client.SetAutoRedirectDisable(true)
+	for _, path := range []string{"/a/relative", "/absolute"} {
+		res, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Close()
+		location, err := res.Response.Location()
+		if err != nil || res.Response.StatusCode != http.StatusFound {
+			t.Fatalf("failed: %v %d", err, res.Response.StatusCode)
+		}
+		kept = append(kept, location.String())
+	}
+	if followed[0] == "/login" && followed[1] == "/login" && kept[0] == server.URL+"/login" && kept[1] == server.URL+"/login" {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %q %q", followed, kept)
+	}
+}
+
+func TestTrailer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Trailer", "X-Checksum")
+		io.WriteString(w, "payload")
+		w.Header().Set("X-Checksum", "abc123")
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	res, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := res.Trailer("X-Checksum")
+	data, _ := res.RawData()
+	if before == "" && string(data) == "payload" && res.Trailer("X-Checksum") == "abc123" {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %q %q", before, res.Trailer("X-Checksum"))
+	}
+}
+
+func TestJSONCodecHooks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(w, r.Body)
+	}))
+	defer server.Close()
+
+	var marshaled, unmarshaled int
+	client := NewClient()
+	client.SetJSONMarshaler(func(v any) ([]byte, error) {
+		marshaled++
+		return json.Marshal(v)
+	})
+	client.SetJSONUnmarshaler(func(data []byte, v any) error {
+		unmarshaled++
+		return json.Unmarshal(data, v)
+	})
+	res, err := client.Post(server.URL, JSONData{"a": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := res.RawJSON()
+	var out struct{ A int }
+	err = client.PostJSON(server.URL, struct{ A int }{2}, &out)
+	if err == nil && marshaled == 2 && unmarshaled == 2 && out.A == 2 && data.(map[string]any)["a"] == 1.0 {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %v %d %d", err, marshaled, unmarshaled)
+	}
+}
+
+func TestConnectionReused(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	var reused []bool
+	for i := 0; i < 2; i++ {
+		res, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.RawData()
+		reused = append(reused, res.ConnectionReused())
+	}
+	if !reused[0] && reused[1] {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %v", reused)
+	}
+}
+
+func TestForceHTTPS(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "http://"+r.Host+"/final", http.StatusFound)
+			return
+		}
+		fmt.Fprintf(w, "%s %v", r.URL.Path, r.TLS != nil)
+	}))
+	defer server.Close()
+	plain := strings.Replace(server.URL, "https://", "http://", 1)
+
+	client := NewClient()
+	client.SetInsecure(true)
+	client.SetForceHTTPS(true)
+	var got []string
+	for _, u := range []string{plain + "/direct", server.URL + "/redirect"} {
+		res, err := client.Get(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := res.RawData()
+		got = append(got, string(data))
+	}
+
+	client.SetRejectHTTP(true)
+	_, err := client.Get(plain + "/direct")
+	if got[0] == "/direct true" && got[1] == "/final true" && errors.Is(err, ErrPlaintextURL) {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %q %v", got, err)
+	}
+}
+
+func TestHeaderOrderRejectsInvalidHeaders(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	client.SetHeaderOrder([]string{"Host", "X-A"})
+	_, valueErr := client.Get(server.URL, Headers{"X-A": "a\r\nInjected: yes"})
+	_, nameErr := client.Get(server.URL, Headers{"X A": "a"})
+	_, methodErr := client.RequestWithMethod("GET /x HTTP/1.1\r\n", server.URL)
+	if valueErr != nil && nameErr != nil && methodErr != nil && calls.Load() == 0 {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %v %v %v, %d calls", valueErr, nameErr, methodErr, calls.Load())
+	}
+}
+
+func TestForwardAuthSameDomainIP(t *testing.T) {
+	client := NewClient()
+	client.SetForwardAuthOnRedirect(true)
+	client.SetForwardAuthSameDomain(true)
+	check := client.checkRedirect()
+
+	origin, _ := http.NewRequest("GET", "http://10.0.0.1/login", nil)
+	origin.Header.Set("Authorization", "Bearer token")
+
+	sameIP, _ := http.NewRequest("GET", "http://10.0.0.1/home", nil)
+	otherIP, _ := http.NewRequest("GET", "http://127.0.0.1/home", nil)
+	if err := check(sameIP, []*http.Request{origin}); err != nil {
+		t.Fatal(err)
+	}
+	if err := check(otherIP, []*http.Request{origin}); err != nil {
+		t.Fatal(err)
+	}
+	if sameIP.Header.Get("Authorization") == "Bearer token" && otherIP.Header.Get("Authorization") == "" {
+		t.Log("succeed")
+	} else {
+		t.Error("failed")
+	}
+}
+
+func TestForceHTTPSDo(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%v", r.TLS != nil)
+	}))
+	defer server.Close()
+	plain := strings.Replace(server.URL, "https://", "http://", 1)
+
+	client := NewClient()
+	client.SetInsecure(true)
+	client.SetForceHTTPS(true)
+	request, err := client.FromCurl("curl " + plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := client.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := res.RawData()
+
+	client.SetRejectHTTP(true)
+	request, _ = client.FromCurl("curl " + plain)
+	_, doErr := client.Do(request)
+	warmErr := client.Warmup(context.Background(), plain, 1)
+	if string(data) == "true" && request.URL.Scheme == "http" && errors.Is(doErr, ErrPlaintextURL) && errors.Is(warmErr, ErrPlaintextURL) {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %s %v %v", data, doErr, warmErr)
+	}
+}
+
+func TestForceHTTPSWebSocket(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.WriteMessage(websocket.TextMessage, []byte("secure"))
+	}))
+	defer server.Close()
+	plain := "ws" + strings.TrimPrefix(server.URL, "https")
+
+	client := NewClient()
+	client.SetInsecure(true)
+	client.SetForceHTTPS(true)
+	conn, _, err := client.WebSocket(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, msg, _ := conn.ReadMessage()
+	conn.Close()
+
+	client.SetRejectHTTP(true)
+	_, _, err = client.WebSocket(plain)
+	if string(msg) == "secure" && errors.Is(err, ErrPlaintextURL) {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %s %v", msg, err)
+	}
+}
+
+func TestTransferCookiesToSecure(t *testing.T) {
+	secure, _ := url.Parse("https://example.com/app")
+	plain, _ := url.Parse("http://example.com/")
+
+	auth, worker := NewClient(), NewClient()
+	if err := auth.SetCookie(secure, &http.Cookie{Name: "session", Value: "s1", Path: "/", Secure: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := auth.TransferCookiesTo(worker, secure); err != nil {
+		t.Fatal(err)
+	}
+	jar, _ := worker.CookieJar()
+	root, _ := url.Parse("https://example.com/")
+	if len(jar.Cookies(root)) == 1 && len(jar.Cookies(plain)) == 0 {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %v %v", jar.Cookies(root), jar.Cookies(plain))
+	}
+}
+
+func TestAPIKeyWithParams(t *testing.T) {
+	client := NewClient()
+	key := APIKey{Key: "api_key", Value: "k2", In: "query"}
+	before, err := client.BuildRequest("GET", "https://example.com/", key, Params{"a": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := client.BuildRequest("GET", "https://example.com/", Params{"a": "1"}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before.URL.RawQuery == "a=1&api_key=k2" && after.URL.RawQuery == "a=1&api_key=k2" {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %s %s", before.URL.RawQuery, after.URL.RawQuery)
+	}
+}
+
+func TestProxyPoolNoFailoverAfterSend(t *testing.T) {
+	var calls atomic.Int32
+	newProxy := func(reset bool) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			if reset {
+				conn, _, _ := w.(http.Hijacker).Hijack()
+				conn.Close()
+			}
+		}))
+	}
+	broken, healthy := newProxy(true), newProxy(false)
+	defer broken.Close()
+	defer healthy.Close()
+
+	client := NewClient()
+	if err := client.SetProxyPool([]string{broken.URL, healthy.URL}, ProxyFailover); err != nil {
+		t.Fatal(err)
+	}
+	_, err := client.Post("http://example.com/", BytesBody{Data: []byte("order")})
+	if err != nil && calls.Load() == 1 {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %v after %d calls", err, calls.Load())
+	}
+}
+
+func TestTLSHandshakeHeaderOrder(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	var calls atomic.Int32
+	client := NewClient()
+	client.SetInsecure(true)
+	client.SetHeaderOrder([]string{"Host", "User-Agent"})
+	client.SetTLSHandshake(func(ctx context.Context, conn net.Conn, cfg *tls.Config) (net.Conn, error) {
+		calls.Add(1)
+		tlsConn := tls.Client(conn, cfg)
+		return tlsConn, tlsConn.HandshakeContext(ctx)
+	})
+	res, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := res.RawData()
+	if string(data) == "ok" && calls.Load() == 1 {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %s after %d handshakes", data, calls.Load())
+	}
+}
+
+func TestTLSHandshakeProxyFunc(t *testing.T) {
+	proxy, _ := url.Parse("http://127.0.0.1:1")
+	client := NewClient()
+	client.SetProxyFunc(http.ProxyURL(proxy))
+	client.SetTLSHandshake(func(ctx context.Context, conn net.Conn, cfg *tls.Config) (net.Conn, error) {
+		return tls.Client(conn, cfg), nil
+	})
+	_, err := client.Get("https://example.com/")
+	if errors.Is(err, ErrTLSHandshakeProxy) {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %v", err)
+	}
+}
+
+func TestParseLinkHeaderComma(t *testing.T) {
+	links := parseLinkHeader([]string{
+		`<https://api.example.com/items?ids=1,2;sort=asc>; title="a, b; c"; rel="next prev",` +
+			` <https://api.example.com/items?page=9>; rel=last`,
+	})
+	if links["next"] == "https://api.example.com/items?ids=1,2;sort=asc" &&
+		links["prev"] == links["next"] &&
+		links["last"] == "https://api.example.com/items?page=9" {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %v", links)
+	}
+}
+
+func TestWebSocketProxyAndJar(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, http.Header{"Set-Cookie": {"ws=2"}})
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		cookie, _ := r.Cookie("sid")
+		if cookie != nil {
+			conn.WriteMessage(websocket.TextMessage, []byte(cookie.Value))
+		}
+	}))
+	defer server.Close()
+
+	var tunnels atomic.Int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			http.Error(w, "connect only", http.StatusMethodNotAllowed)
+			return
+		}
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		tunnels.Add(1)
+		w.WriteHeader(http.StatusOK)
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		go io.Copy(upstream, conn)
+		io.Copy(conn, upstream)
+		conn.Close()
+		upstream.Close()
+	}))
+	defer proxy.Close()
+	proxyURL, _ := url.Parse(proxy.URL)
+	target, _ := url.Parse(server.URL)
+
+	client := NewClient()
+	client.SetProxyFunc(http.ProxyURL(proxyURL))
+	client.SetCookieValue(target, "sid", "abc")
+	conn, _, err := client.WebSocket("ws" + strings.TrimPrefix(server.URL, "http"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, msg, _ := conn.ReadMessage()
+	conn.Close()
+
+	jar, _ := client.CookieJar()
+	cookies := jar.Cookies(target)
+	if string(msg) == "abc" && tunnels.Load() == 1 && len(cookies) == 2 {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %s through %d tunnels with %v", msg, tunnels.Load(), cookies)
+	}
+}
+
+func TestWebSocketTLSHandshake(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.WriteMessage(websocket.TextMessage, []byte("hooked"))
+	}))
+	defer server.Close()
+
+	var calls atomic.Int32
+	client := NewClient()
+	client.SetInsecure(true)
+	client.SetTLSHandshake(func(ctx context.Context, conn net.Conn, cfg *tls.Config) (net.Conn, error) {
+		calls.Add(1)
+		cfg.NextProtos = []string{"http/1.1"}
+		tlsConn := tls.Client(conn, cfg)
+		return tlsConn, tlsConn.HandshakeContext(ctx)
+	})
+	conn, _, err := client.WebSocket("wss" + strings.TrimPrefix(server.URL, "https"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, msg, _ := conn.ReadMessage()
+	conn.Close()
+	if string(msg) == "hooked" && calls.Load() == 1 {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %s after %d handshakes", msg, calls.Load())
+	}
+}
+
+func TestFileBodyNotCompressed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "%q %d %s", r.Header.Get("Content-Encoding"), r.ContentLength, data)
+	}))
+	defer server.Close()
+
+	file := filepath.Join(t.TempDir(), "upload.txt")
+	if err := os.WriteFile(file, []byte("large file"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	client := NewClient()
+	client.EnableCompression()
+	res, err := client.Put(server.URL, FileBody{Path: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := res.RawData()
+	if string(data) == `"" 10 large file` {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %s", data)
+	}
+}
+
+func TestStringRedacted(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	res, err := client.Get(strings.Replace(server.URL, "http://", "http://user:secret@", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Close()
+	summary := res.String()
+	if !strings.Contains(summary, "secret") && strings.Contains(summary, "user:xxxxx@") {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %s", summary)
+	}
+}
+
+func TestMaxConcurrentRequestsUntilClose(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	client.SetMaxConcurrentRequests(1)
+	first, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, blocked := client.RequestWithContext(ctx, "GET", server.URL)
+
+	first.Close()
+	second, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second.Close()
+	if errors.Is(blocked, context.DeadlineExceeded) {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %v", blocked)
+	}
+}
+
+func TestMaxConcurrentRequestsAuthRefresh(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			io.WriteString(w, "new")
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer new" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	client.SetMaxConcurrentRequests(1)
+	client.SetHeader("Authorization", "Bearer old")
+	client.SetAuthRefresher(func(ctx context.Context) error {
+		res, err := client.RequestWithContext(ctx, "GET", server.URL+"/token")
+		if err != nil {
+			return err
+		}
+		token, err := res.RawData()
+		if err != nil {
+			return err
+		}
+		client.SetHeader("Authorization", "Bearer "+string(token))
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	res, err := client.RequestWithContext(ctx, "GET", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := res.RawData()
+	if string(data) == "ok" {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %s", data)
+	}
+}
+
+func TestTee(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, strings.Repeat("tee", 1000))
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	res, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var copied bytes.Buffer
+	res.Tee(&copied)
+	data, err := res.RawData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) == 3000 && bytes.Equal(copied.Bytes(), data) {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %d bytes read, %d copied", len(data), copied.Len())
+	}
+}
+
+func TestDrain(t *testing.T) {
+	var conns atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		size := 1 << 10
+		// large enough that the transport does not drain the rest on Close
+		if r.URL.Path == "/large" {
+			size = 16 << 20
+		}
+		w.Write(bytes.Repeat([]byte("d"), size))
+	}))
+	server.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	client := NewClient()
+	drain := func(path string) bool {
+		res, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.Drain()
+	}
+	small := drain("/small")
+	reused := drain("/small")
+	afterSmall := conns.Load()
+	large := drain("/large")
+	drain("/small")
+	if small && reused && afterSmall == 1 && !large && conns.Load() == 2 {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %v %v %v with %d connections", small, reused, large, conns.Load())
+	}
+}
+
+func TestCheckRedirectOverrides(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "target")
+	}))
+	defer target.Close()
+	targetURL, _ := url.Parse(target.URL)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://localhost:"+targetURL.Port()+"/", http.StatusFound)
+	}))
+	defer server.Close()
+
+	var hops int32
+	client := NewClient()
+	client.SetAutoRedirectDisable(true)
+	client.SetRedirectHostPolicy(false, true)
+	client.SetCheckRedirect(func(req *http.Request, via []*http.Request) error {
+		atomic.AddInt32(&hops, 1)
+		return nil
+	})
+	res, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := res.RawData()
+	if string(data) == "target" && hops == 1 {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %s after %d hops", data, hops)
+	}
+}
+
+func TestHeaderOrderProxyFunc(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer server.Close()
+	proxy, _ := url.Parse("http://127.0.0.1:1")
+
+	client := NewClient()
+	client.SetHeaderOrder([]string{"Host", "User-Agent"})
+	client.SetProxyFunc(http.ProxyURL(proxy))
+	_, err := client.Get(server.URL)
+	checkErr := client.CheckProxy(context.Background(), server.URL)
+	if errors.Is(err, ErrHeaderOrderProxy) && checkErr != nil && calls.Load() == 0 {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %v, %v after %d calls", err, checkErr, calls.Load())
+	}
+}
+
+func TestProxyPoolSocksTargetRefused(t *testing.T) {
+	// newSocks a live SOCKS5 proxy answering every CONNECT with rep=5, connection refused
+	newSocks := func(calls *atomic.Int32) net.Listener {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				calls.Add(1)
+				go func() {
+					defer conn.Close()
+					reader := bufio.NewReader(conn)
+					head := make([]byte, 2)
+					if _, err := io.ReadFull(reader, head); err != nil {
+						return
+					}
+					io.CopyN(io.Discard, reader, int64(head[1]))
+					conn.Write([]byte{5, 0})
+					request := make([]byte, 4)
+					if _, err := io.ReadFull(reader, request); err != nil {
+						return
+					}
+					switch request[3] {
+					case 1:
+						io.CopyN(io.Discard, reader, 4+2)
+					case 3:
+						n, _ := reader.ReadByte()
+						io.CopyN(io.Discard, reader, int64(n)+2)
+					case 4:
+						io.CopyN(io.Discard, reader, 16+2)
+					}
+					conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+				}()
+			}
+		}()
+		return listener
+	}
+	var callsA, callsB atomic.Int32
+	proxyA, proxyB := newSocks(&callsA), newSocks(&callsB)
+	defer proxyA.Close()
+	defer proxyB.Close()
+	dead, _ := net.Listen("tcp", "127.0.0.1:0")
+	dead.Close()
+
+	client := NewClient()
+	proxies := []string{"socks5://" + dead.Addr().String(), "socks5://" + proxyA.Addr().String(), "socks5://" + proxyB.Addr().String()}
+	if err := client.SetProxyPool(proxies, ProxyFailover); err != nil {
+		t.Fatal(err)
+	}
+	_, err1 := client.Get("http://example.com/")
+	_, err2 := client.Get("http://example.com/")
+	if err1 != nil && err2 != nil && callsA.Load() == 2 && callsB.Load() == 0 {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %v, %v with %d/%d calls", err1, err2, callsA.Load(), callsB.Load())
+	}
+}
+
+func TestPaginateRedirect(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/items" {
+			http.Redirect(w, r, "/v2/items", http.StatusFound)
+			return
+		}
+		io.WriteString(w, r.URL.Path)
+	}))
+	defer server.Close()
+
+	var paths []string
+	client := NewClient()
+	err := client.Paginate(server.URL+"/items", func(res *MiniResponse) (string, bool, error) {
+		data, err := res.RawData()
+		if err != nil {
+			return "", false, err
+		}
+		paths = append(paths, string(data))
+		return "page2", len(paths) < 2, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(paths, ",") == "/v2/items,/v2/page2" {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %v", paths)
+	}
+}
+
+func TestSubmitFormRedirect(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/login":
+			http.Redirect(w, r, "/auth/login", http.StatusFound)
+		case r.Method == "GET":
+			io.WriteString(w, `<form action="submit"><input name="user"></form>`)
+		default:
+			r.ParseForm()
+			io.WriteString(w, r.URL.Path+" "+r.PostForm.Encode())
+		}
+	}))
+	defer server.Close()
+
+	opts := make([]any, 1, 2)
+	opts[0] = Headers{"X-A": "1"}
+	spare := opts[:2]
+	spare[1] = Headers{"X-B": "2"}
+
+	client := NewClient()
+	res, err := client.SubmitForm(server.URL+"/login", "", FormKV{"user": "foo"}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := res.RawData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) == "/auth/submit user=foo" && spare[1].(Headers)["X-B"] == "2" {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %s %v", data, spare[1])
+	}
+}
+
+func TestCancelAllBeforeSend(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	builder := client.NewRequest().URL(server.URL)
+	request, err := client.BuildRequest("GET", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.CancelAll()
+
+	res, err := builder.Send()
+	if err != nil {
+		t.Fatal(err)
+	}
+	built, err := client.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := res.RawData()
+	builtData, _ := built.RawData()
+	if string(data) == "ok" && string(builtData) == "ok" {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %s %s", data, builtData)
+	}
+}
+
+func TestResponseBodyTakenGuards(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "multipart/mixed; boundary=b")
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, "stream")
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	res, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := res.Body()
+	defer body.Close()
+
+	var copied bytes.Buffer
+	res.Tee(&copied)
+	statusErr := res.Error()
+	_, multipartErr := res.MultipartReader()
+	_, frameErr := res.FrameReader(Uint32BigEndian).Next()
+	data, _ := io.ReadAll(body)
+
+	var se *StatusError
+	if string(data) == "stream" && copied.Len() == 0 && errors.As(statusErr, &se) && se.Snippet == "" &&
+		errors.Is(multipartErr, ErrBodyTaken) && errors.Is(frameErr, ErrBodyTaken) {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %s %v %v %v", data, statusErr, multipartErr, frameErr)
+	}
+}
+
+func TestConfig(t *testing.T) {
+	client := NewClient()
+	client.SetTimeout(5)
+	client.SetProxyFunc(http.ProxyFromEnvironment)
+	client.SetTransport(&http.Transport{})
+	client.SetMaxConcurrentRequests(3)
+	client.SetHeader("X-Token", "abc")
+	client.SetHeaderOrder([]string{"Host", "User-Agent"})
+	client.SetForceHTTPS(true)
+
+	cfg := client.Config()
+	cfg.Headers.Set("X-Token", "changed")
+	cfg.HeaderOrder[0] = "Accept"
+
+	again := client.Config()
+	if cfg.Timeout == 5*time.Second && cfg.ProxyFunc && cfg.CustomTransport &&
+		cfg.MaxConcurrentRequests == 3 && cfg.ForceHTTPS && !cfg.TLSHandshake &&
+		again.Headers.Get("X-Token") == "abc" && strings.Join(again.HeaderOrder, ",") == "Host,User-Agent" {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %+v", again)
+	}
+}
+
+func TestConditionalHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Header.Get("If-Match")+" "+r.Header.Get("If-None-Match"))
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	res, err := client.Put(server.URL, IfMatch(`"v1"`), IfNoneMatch(`"v2"`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := res.RawData()
+	if string(data) == `"v1" "v2"` {
+		t.Log("succeed")
+	} else {
+		t.Errorf("failed: %s", data)
+	}
+}
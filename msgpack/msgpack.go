@@ -0,0 +1,49 @@
+// Package msgpack MessagePack support for minireq
+package msgpack
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	"github.com/qmaru/minireq/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// ContentType MessagePack media type
+const ContentType = "application/msgpack"
+
+// MsgpackData Use application/msgpack
+type MsgpackData map[string]any
+
+// Register Teach client to encode MsgpackData options
+func Register(client *minireq.HttpClient) {
+	client.RegisterEncoder(MsgpackData{}, encode)
+}
+
+func encode(request *http.Request, opt any) error {
+	data, err := msgpack.Marshal(opt)
+	if err != nil {
+		return err
+	}
+	reader := bytes.NewReader(data)
+	snapshot := *reader
+
+	request.Header.Set("Content-Type", ContentType)
+	request.ContentLength = int64(reader.Len())
+	request.Body = io.NopCloser(reader)
+	request.GetBody = func() (io.ReadCloser, error) {
+		r := snapshot
+		return io.NopCloser(&r), nil
+	}
+	return nil
+}
+
+// BindMsgpack Decode the body into v
+func BindMsgpack(res *minireq.MiniResponse, v any) error {
+	rawData, err := res.RawData()
+	if err != nil {
+		return err
+	}
+	return msgpack.Unmarshal(rawData, v)
+}
@@ -0,0 +1,37 @@
+package msgpack
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/qmaru/minireq/v2"
+)
+
+func TestMsgpackData(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+		io.Copy(w, r.Body)
+	}))
+	defer server.Close()
+
+	client := minireq.NewClient()
+	Register(client)
+	res, err := client.Post(server.URL, MsgpackData{"foo": "bar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out struct {
+		Foo string `msgpack:"foo"`
+	}
+	if err := BindMsgpack(res, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Foo == "bar" && res.Response.Header.Get("Content-Type") == ContentType {
+		t.Log("succeed")
+	} else {
+		t.Error("failed")
+	}
+}
@@ -0,0 +1,199 @@
+package minireq
+
+import (
+	"errors"
+	URL "net/url"
+	"strings"
+)
+
+// ErrNoMorePages No next page to fetch
+var ErrNoMorePages = errors.New("no more pages")
+
+// PageIterator Follow rel="next" Link headers
+type PageIterator struct {
+	client  *HttpClient
+	nextURL string
+	opts    []any
+}
+
+// GetPaginated Iterate pages linked by the Link header
+func (h *HttpClient) GetPaginated(url string, opts ...any) (*PageIterator, error) {
+	if _, err := URL.Parse(url); err != nil {
+		return nil, err
+	}
+	return &PageIterator{
+		client:  h,
+		nextURL: url,
+		opts:    opts,
+	}, nil
+}
+
+// HasNext Report whether another page is available
+func (p *PageIterator) HasNext() bool {
+	return p.nextURL != ""
+}
+
+// Next Fetch the next page
+func (p *PageIterator) Next() (*MiniResponse, error) {
+	if p.nextURL == "" {
+		return nil, ErrNoMorePages
+	}
+	res, err := p.client.RequestWithMethod("GET", p.nextURL, p.opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	p.nextURL = ""
+	if next := parseLinkHeader(res.Response.Header.Values("Link"))["next"]; next != "" {
+		// relative to the final url, after redirects
+		if u, err := res.Response.Request.URL.Parse(next); err == nil {
+			p.nextURL = u.String()
+		}
+	}
+	// the next link carries its own query
+	if p.nextURL != "" {
+		p.opts = dropParams(p.opts)
+	}
+	return res, nil
+}
+
+// Paginate Call url and each page returned by next until more is false
+//
+// next receives every response and extracts the following page url, which is
+// resolved against the final url of the current one and replaces any Params
+// option.
+func (h *HttpClient) Paginate(url string, next func(res *MiniResponse) (nextURL string, more bool, err error), opts ...any) error {
+	for {
+		res, err := h.RequestWithMethod("GET", url, opts...)
+		if err != nil {
+			return err
+		}
+
+		nextURL, more, err := next(res)
+		res.Response.Body.Close()
+		if err != nil {
+			return err
+		}
+		if !more {
+			return nil
+		}
+
+		u, err := res.Response.Request.URL.Parse(nextURL)
+		if err != nil {
+			return err
+		}
+		url = u.String()
+		opts = dropParams(opts)
+	}
+}
+
+// parseLinkHeader parse RFC 5988 Link headers into rel -> url
+//
+// Targets are scanned as <...> so they may contain commas and semicolons,
+// parameter values may be quoted strings.
+func parseLinkHeader(values []string) map[string]string {
+	links := make(map[string]string)
+	for _, value := range values {
+		s := value
+		for {
+			s = strings.TrimLeft(s, " \t,")
+			if s == "" {
+				break
+			}
+			if s[0] != '<' {
+				s = skipLink(s)
+				continue
+			}
+			end := strings.IndexByte(s, '>')
+			if end < 0 {
+				break
+			}
+			target := s[1:end]
+			s = s[end+1:]
+
+			for {
+				s = strings.TrimLeft(s, " \t")
+				if s == "" || s[0] != ';' {
+					break
+				}
+				var key, val string
+				key, val, s = parseLinkParam(s[1:])
+				if !strings.EqualFold(key, "rel") {
+					continue
+				}
+				for _, rel := range strings.Fields(val) {
+					rel = strings.ToLower(rel)
+					if _, ok := links[rel]; !ok {
+						links[rel] = target
+					}
+				}
+			}
+			s = skipLink(s)
+		}
+	}
+	return links
+}
+
+// parseLinkParam parse key=value or key="quoted value", return the rest of s
+func parseLinkParam(s string) (key, val, rest string) {
+	s = strings.TrimLeft(s, " \t")
+	end := strings.IndexAny(s, "=;,")
+	if end < 0 {
+		return strings.TrimSpace(s), "", ""
+	}
+	key = strings.TrimSpace(s[:end])
+	if s[end] != '=' {
+		return key, "", s[end:]
+	}
+	s = strings.TrimLeft(s[end+1:], " \t")
+	if !strings.HasPrefix(s, `"`) {
+		end = strings.IndexAny(s, ";,")
+		if end < 0 {
+			return key, strings.TrimSpace(s), ""
+		}
+		return key, strings.TrimSpace(s[:end]), s[end:]
+	}
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				b.WriteByte(s[i])
+			}
+		case '"':
+			return key, b.String(), s[i+1:]
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return key, b.String(), ""
+}
+
+// skipLink drop the rest of the current link, up to a comma outside quotes
+func skipLink(s string) string {
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case quoted && s[i] == '\\':
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case s[i] == ',' && !quoted:
+			return s[i+1:]
+		}
+	}
+	return ""
+}
+
+// dropParams remove Params options
+func dropParams(opts []any) []any {
+	kept := make([]any, 0, len(opts))
+	for _, opt := range opts {
+		if _, ok := opt.(Params); ok {
+			continue
+		}
+		kept = append(kept, opt)
+	}
+	return kept
+}
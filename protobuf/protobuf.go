@@ -0,0 +1,51 @@
+// Package protobuf Protocol Buffers support for minireq
+package protobuf
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	"github.com/qmaru/minireq/v2"
+	"google.golang.org/protobuf/proto"
+)
+
+// ContentType Protobuf media type
+const ContentType = "application/x-protobuf"
+
+// ProtoBody Use application/x-protobuf
+type ProtoBody struct {
+	Message proto.Message
+}
+
+// Register Teach client to encode ProtoBody options
+func Register(client *minireq.HttpClient) {
+	client.RegisterEncoder(ProtoBody{}, encode)
+}
+
+func encode(request *http.Request, opt any) error {
+	data, err := proto.Marshal(opt.(ProtoBody).Message)
+	if err != nil {
+		return err
+	}
+	reader := bytes.NewReader(data)
+	snapshot := *reader
+
+	request.Header.Set("Content-Type", ContentType)
+	request.ContentLength = int64(reader.Len())
+	request.Body = io.NopCloser(reader)
+	request.GetBody = func() (io.ReadCloser, error) {
+		r := snapshot
+		return io.NopCloser(&r), nil
+	}
+	return nil
+}
+
+// BindProto Decode the body into m
+func BindProto(res *minireq.MiniResponse, m proto.Message) error {
+	rawData, err := res.RawData()
+	if err != nil {
+		return err
+	}
+	return proto.Unmarshal(rawData, m)
+}
@@ -0,0 +1,36 @@
+package protobuf
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/qmaru/minireq/v2"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestProtoBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+		io.Copy(w, r.Body)
+	}))
+	defer server.Close()
+
+	client := minireq.NewClient()
+	Register(client)
+	res, err := client.Post(server.URL, ProtoBody{Message: wrapperspb.String("bar")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out wrapperspb.StringValue
+	if err := BindProto(res, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.GetValue() == "bar" && res.Response.Header.Get("Content-Type") == ContentType {
+		t.Log("succeed")
+	} else {
+		t.Error("failed")
+	}
+}
@@ -0,0 +1,101 @@
+package minireq
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	URL "net/url"
+	"sync/atomic"
+)
+
+// ProxyPoolMode How SetProxyPool picks a proxy
+type ProxyPoolMode int
+
+const (
+	// ProxyRoundRobin Rotate through the proxies, one per request
+	ProxyRoundRobin ProxyPoolMode = iota
+	// ProxyFailover Stay on a proxy until connecting through it fails
+	ProxyFailover
+)
+
+// proxyPool proxies shared by SetProxyPool
+type proxyPool struct {
+	proxies []*URL.URL
+	mode    ProxyPoolMode
+	next    atomic.Uint64
+}
+
+// SetProxyPool Send requests through proxies, e.g. "socks5://127.0.0.1:1080" or "http://proxy:8080"
+//
+// In failover mode failing to reach a proxy moves every later request to the
+// next one and the failed request is retried there, once per proxy, when its
+// body can be replayed. Errors after the request was sent are returned as is.
+// Takes precedence over SetProxy, empty proxies removes the pool.
+func (h *HttpClient) SetProxyPool(proxies []string, mode ProxyPoolMode) error {
+	pool := &proxyPool{mode: mode}
+	for _, p := range proxies {
+		u, err := URL.Parse(p)
+		if err != nil {
+			return err
+		}
+		pool.proxies = append(pool.proxies, u)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(pool.proxies) == 0 {
+		h.proxyFunc, h.proxyPool = nil, nil
+		return nil
+	}
+	fn := ProxyFunc(pool.proxy)
+	h.proxyFunc, h.proxyPool = &fn, pool
+	return nil
+}
+
+// proxy the proxy for req, ProxyFunc of the pool
+func (p *proxyPool) proxy(req *http.Request) (*URL.URL, error) {
+	if p.mode == ProxyFailover {
+		return p.proxies[p.next.Load()%uint64(len(p.proxies))], nil
+	}
+	return p.proxies[(p.next.Add(1)-1)%uint64(len(p.proxies))], nil
+}
+
+// failover send req, moving to the next proxy on connection errors
+func (p *proxyPool) failover(transport http.RoundTripper, req *http.Request) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		current := p.next.Load()
+		resp, err := transport.RoundTrip(req)
+		if err == nil || !isProxyConnectError(err) {
+			return resp, err
+		}
+		p.next.CompareAndSwap(current, current+1)
+		if attempt >= len(p.proxies) || (req.Body != nil && req.GetBody == nil) {
+			return nil, err
+		}
+		retry := req.Clone(req.Context())
+		if req.GetBody != nil {
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return nil, err
+			}
+			retry.Body = body
+		}
+		req = retry
+	}
+}
+
+// isProxyConnectError the dial to the proxy itself failed
+//
+// "proxyconnect" and "socks connect" also wrap a live proxy failing to reach
+// the target, only a nested dial error blames the proxy.
+func isProxyConnectError(err error) bool {
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) {
+		return false
+	}
+	if opErr.Op == "dial" {
+		return true
+	}
+	var inner *net.OpError
+	return errors.As(opErr.Err, &inner) && inner.Op == "dial"
+}
@@ -1,19 +1,126 @@
 package minireq
 
 import (
+	"bytes"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/html/charset"
 )
 
+// drainLimit Max bytes discarded by Drain
+const drainLimit = 256 << 10
+
+// defaultSnippetLen Body bytes quoted in errors
+const defaultSnippetLen = 512
+
 type MiniResponse struct {
 	Request  *http.Request
 	Response *http.Response
+
+	attempts  int
+	elapsed   time.Duration
+	reused    bool
+	unmarshal func([]byte, any) error
+	redirects []RedirectHop
+	bodyTaken bool
+}
+
+// Body Take ownership of the live body, the caller must close it
+//
+// Afterwards RawData and the helpers built on it return ErrBodyTaken, Drain
+// and Close do nothing.
+func (res *MiniResponse) Body() io.ReadCloser {
+	res.bodyTaken = true
+	return res.Response.Body
+}
+
+// Close Close the body unless Body handed it to the caller
+func (res *MiniResponse) Close() error {
+	if res.bodyTaken {
+		return nil
+	}
+	return res.Response.Body.Close()
+}
+
+// miniResPool responses recycled by Release
+var miniResPool = sync.Pool{
+	New: func() any {
+		return new(MiniResponse)
+	},
+}
+
+// Release Return the response to the pool for reuse by later requests
+//
+// Optional, call it after Close on hot paths. The response and anything
+// obtained from it, the body included, must not be used afterwards.
+func (res *MiniResponse) Release() {
+	*res = MiniResponse{}
+	miniResPool.Put(res)
+}
+
+// RedirectHop A redirect followed on the way to the response
+type RedirectHop struct {
+	URL        string // url that answered with the redirect
+	StatusCode int
+	Location   string
+}
+
+// RedirectChain Redirects followed, in order, when SetRecordRedirects is on
+func (res *MiniResponse) RedirectChain() []RedirectHop {
+	return res.redirects
+}
+
+// ContentLength Declared body size without reading it, -1 when unknown
+func (res *MiniResponse) ContentLength() int64 {
+	if res.Response.ContentLength >= 0 {
+		return res.Response.ContentLength
+	}
+	if n, err := strconv.ParseInt(res.Response.Header.Get("Content-Length"), 10, 64); err == nil && n >= 0 {
+		return n
+	}
+	return -1
+}
+
+// ConnectionReused Whether the request went over a pooled connection rather than a new one
+//
+// Always false with SetHeaderOrder or a SetTransport transport without httptrace support.
+func (res *MiniResponse) ConnectionReused() bool {
+	return res.reused
+}
+
+// Trailer Value of trailer key, only set once the body has been read to the end
+func (res *MiniResponse) Trailer(key string) string {
+	return res.Response.Trailer.Get(key)
+}
+
+// Attempts Times the request was sent, more than 1 when it was replayed
+func (res *MiniResponse) Attempts() int {
+	return res.attempts
+}
+
+// Elapsed Time from sending the request until the headers arrived, replays included
+//
+// With SetBodyReadRetries the body is buffered first and counts too.
+func (res *MiniResponse) Elapsed() time.Duration {
+	return res.elapsed
 }
 
 // RawData bytes data
 func (res *MiniResponse) RawData() ([]byte, error) {
+	if res.bodyTaken {
+		return nil, ErrBodyTaken
+	}
 	body := res.Response.Body
 	defer body.Close()
 
@@ -24,6 +131,175 @@ func (res *MiniResponse) RawData() ([]byte, error) {
 	return bodyData, nil
 }
 
+// Drain Discard the unread body and close it
+//
+// Call it when abandoning a response so the connection can return to the
+// keep-alive pool. It reports whether the body was fully consumed, bodies
+// larger than 256KB are not drained and their connection is not reused.
+func (res *MiniResponse) Drain() bool {
+	if res.bodyTaken {
+		return false
+	}
+	body := res.Response.Body
+	defer body.Close()
+
+	n, err := io.Copy(io.Discard, io.LimitReader(body, drainLimit+1))
+	return err == nil && n <= drainLimit
+}
+
+// Tee Copy the body to w while it is read
+//
+// Must be called before RawData, RawJSON or any other read, no effect after Body.
+func (res *MiniResponse) Tee(w io.Writer) {
+	if res.bodyTaken {
+		return
+	}
+	body := res.Response.Body
+	res.Response.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.TeeReader(body, w), body}
+}
+
+// MultipartReader Iterate the parts of a multipart/* body, nothing is cached
+func (res *MiniResponse) MultipartReader() (*multipart.Reader, error) {
+	if res.bodyTaken {
+		return nil, ErrBodyTaken
+	}
+	mediaType, params, err := mime.ParseMediaType(res.Response.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		return nil, fmt.Errorf("not a multipart response: %s", mediaType)
+	}
+	boundary := params["boundary"]
+	if boundary == "" {
+		return nil, errors.New("multipart boundary is missing")
+	}
+	return multipart.NewReader(res.Response.Body, boundary), nil
+}
+
+// CSVReader Iterate the rows of the body, nothing is cached
+//
+// A charset parameter in Content-Type other than utf-8 is decoded to UTF-8.
+func (res *MiniResponse) CSVReader() (*csv.Reader, error) {
+	if res.bodyTaken {
+		return nil, ErrBodyTaken
+	}
+	var body io.Reader = res.Response.Body
+	_, params, _ := mime.ParseMediaType(res.Response.Header.Get("Content-Type"))
+	if label := params["charset"]; label != "" && !strings.EqualFold(label, "utf-8") {
+		enc, _ := charset.Lookup(label)
+		if enc == nil {
+			return nil, fmt.Errorf("unsupported charset: %s", label)
+		}
+		body = enc.NewDecoder().Reader(body)
+	}
+	return csv.NewReader(body), nil
+}
+
+// snippet Peek at the start of the body, leaving it readable in full
+func (res *MiniResponse) snippet(n int) string {
+	body := res.Response.Body
+	head, _ := io.ReadAll(io.LimitReader(body, int64(n)))
+	res.Response.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(head), body), body}
+	return string(head)
+}
+
+// checkContentType compare the media type with expected
+func (res *MiniResponse) checkContentType(expected string, snippetLen int) error {
+	actual := res.Response.Header.Get("Content-Type")
+	mediaType, _, err := mime.ParseMediaType(actual)
+	if err == nil && strings.EqualFold(mediaType, expected) {
+		return nil
+	}
+	return &ContentTypeError{
+		Expected: expected,
+		Actual:   actual,
+		Snippet:  res.snippet(snippetLen),
+	}
+}
+
+// BindJSON Decode the body into v
+func (res *MiniResponse) BindJSON(v any) error {
+	rawData, err := res.RawData()
+	if err != nil {
+		return err
+	}
+	return res.jsonUnmarshal(rawData, v)
+}
+
+// jsonUnmarshal decode with the client's SetJSONUnmarshaler
+func (res *MiniResponse) jsonUnmarshal(data []byte, v any) error {
+	if res.unmarshal == nil {
+		return json.Unmarshal(data, v)
+	}
+	return res.unmarshal(data, v)
+}
+
+// Error Describe a non-2xx response as a *StatusError, nil for 2xx
+//
+// The message comes from a JSON error envelope when there is one, the body
+// is cached so it can still be read afterwards. After Body only the status
+// is reported, the stream is left alone.
+func (res *MiniResponse) Error() error {
+	return res.statusError(defaultSnippetLen)
+}
+
+// statusError Error quoting up to snippetLen body bytes
+func (res *MiniResponse) statusError(snippetLen int) error {
+	code := res.Response.StatusCode
+	if code >= 200 && code < 300 {
+		return nil
+	}
+
+	statusErr := &StatusError{StatusCode: code, Status: res.Response.Status}
+	if statusErr.Status == "" {
+		statusErr.Status = fmt.Sprintf("%d %s", code, http.StatusText(code))
+	}
+	if res.bodyTaken {
+		return statusErr
+	}
+
+	body := res.Response.Body
+	data, err := io.ReadAll(body)
+	body.Close()
+	res.Response.Body = io.NopCloser(bytes.NewReader(data))
+	if err == nil {
+		statusErr.Message = errorMessage(data)
+		if len(data) > snippetLen {
+			data = data[:snippetLen]
+		}
+		statusErr.Snippet = string(data)
+	}
+	return statusErr
+}
+
+// errorMessage message of common JSON error envelopes
+func errorMessage(data []byte) string {
+	var envelope map[string]any
+	if json.Unmarshal(data, &envelope) != nil {
+		return ""
+	}
+	for _, key := range []string{"message", "error_description", "error", "detail", "title"} {
+		switch v := envelope[key].(type) {
+		case string:
+			if v != "" {
+				return v
+			}
+		case map[string]any:
+			if msg, ok := v["message"].(string); ok && msg != "" {
+				return msg
+			}
+		}
+	}
+	return ""
+}
+
 // RawJSON JSON data
 func (res *MiniResponse) RawJSON() (any, error) {
 	var jsonData any
@@ -31,7 +307,7 @@ func (res *MiniResponse) RawJSON() (any, error) {
 	if err != nil {
 		return nil, err
 	}
-	err = json.Unmarshal(rawData, &jsonData)
+	err = res.jsonUnmarshal(rawData, &jsonData)
 	if err != nil {
 		return nil, err
 	}
@@ -55,3 +331,15 @@ func (res *MiniResponse) RawNumJSON() (any, error) {
 	}
 	return jsonData, nil
 }
+
+// String Summary of the exchange, the body is not consumed and the password is redacted
+func (res *MiniResponse) String() string {
+	if res == nil || res.Request == nil || res.Response == nil {
+		return "<nil>"
+	}
+	size := "unknown length"
+	if res.Response.ContentLength >= 0 {
+		size = fmt.Sprintf("%d bytes", res.Response.ContentLength)
+	}
+	return fmt.Sprintf("%s %s -> %s (%s)", res.Request.Method, res.Request.URL.Redacted(), res.Response.Status, size)
+}
@@ -0,0 +1,113 @@
+package minireq
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync/atomic"
+	"time"
+)
+
+// SetReadTimeout Fail a body Read that waits longer than d for data, 0 disables
+//
+// The deadline is set on the connection before every Read, so a server that
+// stalls mid-body is caught even when the request timeout is generous. The
+// error matches ErrReadTimeout. HTTP/2 responses and bodies buffered by
+// SetBodyReadRetries are not covered.
+func (h *HttpClient) SetReadTimeout(d time.Duration) {
+	h.ReadTimeout = d
+}
+
+// SetFirstByteTimeout Abort the request when no body byte arrives within d of the headers, 0 disables
+//
+// The error matches ErrTimeout. Bodies buffered by SetBodyReadRetries are not
+// covered.
+func (h *HttpClient) SetFirstByteTimeout(d time.Duration) {
+	h.FirstByteTimeout = d
+}
+
+// traceConn record the connection that serves request, the last one after redirects
+func traceConn(request *http.Request, conn *httptrace.GotConnInfo) *http.Request {
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			*conn = info
+		},
+	}
+	return request.WithContext(httptrace.WithClientTrace(request.Context(), trace))
+}
+
+// readTimeoutBody applies a read deadline to conn around every Read
+type readTimeoutBody struct {
+	body    io.ReadCloser
+	conn    net.Conn
+	timeout time.Duration
+	done    bool
+}
+
+func (b *readTimeoutBody) Read(p []byte) (int, error) {
+	if b.done {
+		return b.body.Read(p)
+	}
+	b.conn.SetReadDeadline(time.Now().Add(b.timeout))
+	n, err := b.body.Read(p)
+	b.conn.SetReadDeadline(time.Time{})
+	if err != nil {
+		// the connection may be back in the pool, leave it alone
+		b.done = true
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return n, &RequestError{Kind: ErrReadTimeout, Err: err}
+		}
+	}
+	return n, err
+}
+
+func (b *readTimeoutBody) Close() error {
+	b.done = true
+	return b.body.Close()
+}
+
+// withCancel make request cancelable
+func withCancel(request *http.Request) (*http.Request, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(request.Context())
+	return request.WithContext(ctx), cancel
+}
+
+// firstByteBody cancels the request unless a byte is read before the timer fires
+type firstByteBody struct {
+	io.ReadCloser
+	timer  *time.Timer
+	cancel context.CancelFunc
+	fired  atomic.Bool
+}
+
+// newFirstByteBody start the timer on body
+func newFirstByteBody(body io.ReadCloser, cancel context.CancelFunc, timeout time.Duration) *firstByteBody {
+	b := &firstByteBody{ReadCloser: body, cancel: cancel}
+	b.timer = time.AfterFunc(timeout, func() {
+		b.fired.Store(true)
+		cancel()
+	})
+	return b
+}
+
+func (b *firstByteBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.timer.Stop()
+	}
+	if err != nil && b.fired.Load() {
+		return n, &RequestError{Kind: ErrTimeout, Err: err}
+	}
+	return n, err
+}
+
+func (b *firstByteBody) Close() error {
+	b.timer.Stop()
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,315 @@
+package minireq
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	URL "net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// maxIdleConnsPerHost idle connections kept per host, the bound of Warmup
+const maxIdleConnsPerHost = 64
+
+// transportConfig settings baked into a transport
+type transportConfig struct {
+	Socks5Address string
+	Insecure      bool
+	ServerName    string
+	H2C           bool
+	HeaderOrder   string
+	IdleTimeout   int
+	ProtoMajor    int
+	ProtoMinor    int
+	ProxyFunc     *ProxyFunc
+	ProxyHeader   *http.Header
+	TLSHandshake  *TLSHandshake
+}
+
+// transportFunc adapt a function to http.RoundTripper
+type transportFunc func(*http.Request) (*http.Response, error)
+
+func (f transportFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// roundTrip send through the transport for req.URL, redirects included
+func (h *HttpClient) roundTrip(req *http.Request) (*http.Response, error) {
+	transport, err := h.getTransport(req.URL)
+	if err != nil {
+		return nil, err
+	}
+	h.mu.Lock()
+	pool := h.proxyPool
+	h.mu.Unlock()
+	if pool != nil && pool.mode == ProxyFailover {
+		return pool.failover(transport, req)
+	}
+	return transport.RoundTrip(req)
+}
+
+// getTransport shared transport for u, rebuilt when the settings change
+func (h *HttpClient) getTransport(u *URL.URL) (http.RoundTripper, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.transport != nil {
+		return h.transport, nil
+	}
+	cfg := transportConfig{
+		Socks5Address: h.Socks5Address,
+		Insecure:      h.Insecure,
+		ServerName:    h.TLSServerName,
+		H2C:           h.H2C,
+		HeaderOrder:   strings.Join(h.HeaderOrder, "\n"),
+		IdleTimeout:   h.IdleConnTimeout,
+		ProtoMajor:    h.ProtoMajor,
+		ProtoMinor:    h.ProtoMinor,
+	}
+	key := ""
+	if h.PerHostTransport {
+		key = u.Scheme + "://" + u.Host
+	}
+	cfg.ProxyFunc = h.proxyFunc
+	cfg.ProxyHeader = h.proxyHeader
+	cfg.TLSHandshake = h.tlsHandshake
+	if cfg != h.transportCfg {
+		h.closeIdleTransports()
+		h.transports = nil
+		h.transportCfg = cfg
+	}
+	if transport, ok := h.transports[key]; ok {
+		return transport, nil
+	}
+
+	transport, err := h.newTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if h.transports == nil {
+		h.transports = make(map[string]http.RoundTripper)
+	}
+	h.transports[key] = transport
+	return transport, nil
+}
+
+// TransportConfigBuilder Transport settings changed together by SetTransportConfig
+type TransportConfigBuilder struct {
+	Socks5Address    string
+	Insecure         bool
+	TLSServerName    string
+	H2C              bool
+	HeaderOrder      []string
+	IdleConnTimeout  int // seconds
+	ProtoMajor       int
+	ProtoMinor       int
+	PerHostTransport bool
+}
+
+// SetTransportConfig Change several transport settings at once
+//
+// fn sees the current settings. Requests never observe a partial update and
+// the transports are rebuilt once, on the next request.
+func (h *HttpClient) SetTransportConfig(fn func(cfg *TransportConfigBuilder)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	cfg := &TransportConfigBuilder{
+		Socks5Address:    h.Socks5Address,
+		Insecure:         h.Insecure,
+		TLSServerName:    h.TLSServerName,
+		H2C:              h.H2C,
+		HeaderOrder:      append([]string(nil), h.HeaderOrder...),
+		IdleConnTimeout:  h.IdleConnTimeout,
+		ProtoMajor:       h.ProtoMajor,
+		ProtoMinor:       h.ProtoMinor,
+		PerHostTransport: h.PerHostTransport,
+	}
+	fn(cfg)
+	h.Socks5Address = cfg.Socks5Address
+	h.Insecure = cfg.Insecure
+	h.TLSServerName = cfg.TLSServerName
+	h.H2C = cfg.H2C
+	h.HeaderOrder = cfg.HeaderOrder
+	h.IdleConnTimeout = cfg.IdleConnTimeout
+	h.ProtoMajor, h.ProtoMinor = cfg.ProtoMajor, cfg.ProtoMinor
+	h.PerHostTransport = cfg.PerHostTransport
+}
+
+// newTransport build a transport from cfg
+func (h *HttpClient) newTransport(cfg transportConfig) (http.RoundTripper, error) {
+	if cfg.TLSHandshake != nil && cfg.ProxyFunc != nil {
+		// net/http runs its own handshake through CONNECT tunnels
+		return nil, ErrTLSHandshakeProxy
+	}
+	if cfg.HeaderOrder != "" {
+		// the ordered transport only dials directly or through socks5
+		if cfg.ProxyFunc != nil {
+			return nil, ErrHeaderOrderProxy
+		}
+		return h.newOrderedTransport(cfg)
+	}
+	clientTransport := new(http.Transport)
+	clientTransport.IdleConnTimeout = time.Duration(cfg.IdleTimeout) * time.Second
+	clientTransport.MaxIdleConnsPerHost = maxIdleConnsPerHost
+	// allow proxy
+	if cfg.ProxyFunc != nil {
+		clientTransport.Proxy = *cfg.ProxyFunc
+		if cfg.ProxyHeader != nil {
+			clientTransport.ProxyConnectHeader = *cfg.ProxyHeader
+		}
+	} else {
+		dialContext, err := h.proxyDialContext()
+		if err != nil {
+			return nil, err
+		}
+		if dialContext != nil {
+			clientTransport.Proxy = nil
+			clientTransport.DialContext = dialContext
+			clientTransport.TLSHandshakeTimeout = time.Duration(30) * time.Second
+		}
+	}
+	if cfg.Insecure || cfg.ServerName != "" {
+		clientTransport.TLSClientConfig = &tls.Config{InsecureSkipVerify: cfg.Insecure, ServerName: cfg.ServerName}
+	}
+	switch cfg.ProtoMajor {
+	case 1:
+		// a non-nil empty map turns HTTP/2 off
+		clientTransport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+		clientTransport.DisableKeepAlives = cfg.ProtoMinor == 0
+	case 2:
+		clientTransport.ForceAttemptHTTP2 = true
+	}
+	if cfg.TLSHandshake != nil {
+		clientTransport.DialTLSContext = tlsDialContext(clientTransport.DialContext, *cfg.TLSHandshake, cfg.ServerName, cfg.Insecure)
+	}
+	if cfg.H2C {
+		return newH2CTransport(clientTransport), nil
+	}
+	return clientTransport, nil
+}
+
+// tlsDialContext dial TCP then hand the connection to handshake
+func tlsDialContext(dialContext func(ctx context.Context, network, address string) (net.Conn, error), handshake TLSHandshake, serverName string, insecure bool) func(ctx context.Context, network, address string) (net.Conn, error) {
+	if dialContext == nil {
+		dialContext = (&net.Dialer{Timeout: 30 * time.Second}).DialContext
+	}
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		if serverName != "" {
+			host = serverName
+		}
+		conn, err := dialContext(ctx, network, address)
+		if err != nil {
+			return nil, err
+		}
+		tlsConn, err := handshake(ctx, conn, &tls.Config{ServerName: host, InsecureSkipVerify: insecure})
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return tlsConn, nil
+	}
+}
+
+// newOrderedTransport transport honoring HeaderOrder, the socks5 proxy, Insecure and the TLS hook apply
+func (h *HttpClient) newOrderedTransport(cfg transportConfig) (http.RoundTripper, error) {
+	dialContext, err := h.proxyDialContext()
+	if err != nil {
+		return nil, err
+	}
+	if dialContext == nil {
+		dialContext = (&net.Dialer{Timeout: 30 * time.Second}).DialContext
+	}
+	return &orderedTransport{
+		order:       strings.Split(cfg.HeaderOrder, "\n"),
+		dialContext: dialContext,
+		tlsConfig:   &tls.Config{InsecureSkipVerify: cfg.Insecure, ServerName: cfg.ServerName},
+		handshake:   cfg.TLSHandshake,
+	}, nil
+}
+
+// closeIdleTransports close idle connections of every cached transport, h.mu held
+func (h *HttpClient) closeIdleTransports() {
+	for _, transport := range h.transports {
+		if t, ok := transport.(interface{ CloseIdleConnections() }); ok {
+			t.CloseIdleConnections()
+		}
+	}
+}
+
+// Warmup Open n connections to the host of url and leave them idle in the pool
+//
+// Each connection is established with a concurrent HEAD request through the
+// shared transport, so proxy and TLS settings apply. The transport keeps at
+// most 64 idle connections per host, a larger n is lowered to that.
+func (h *HttpClient) Warmup(ctx context.Context, url string, n int) error {
+	if n > maxIdleConnsPerHost {
+		n = maxIdleConnsPerHost
+	}
+	u, err := URL.Parse(url)
+	if err != nil {
+		return err
+	}
+	if err := h.secureURL(u); err != nil {
+		return err
+	}
+	transport, err := h.getTransport(u)
+	if err != nil {
+		return err
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	for i := 0; i < n; i++ {
+		g.Go(func() error {
+			request, err := http.NewRequestWithContext(ctx, "HEAD", u.String(), nil)
+			if err != nil {
+				return err
+			}
+			request.Header.Set("User-Agent", DefaultUA)
+			response, err := transport.RoundTrip(request)
+			if err != nil {
+				return classifyError(err)
+			}
+			io.Copy(io.Discard, response.Body)
+			return response.Body.Close()
+		})
+	}
+	return g.Wait()
+}
+
+// CheckProxy Send a GET to testURL through the configured proxy, a preflight for proxy workflows
+//
+// Failures, non-2xx answers included, match ErrProxyFailure.
+func (h *HttpClient) CheckProxy(ctx context.Context, testURL string) error {
+	h.mu.Lock()
+	configured := h.Socks5Address != "" || h.proxyFunc != nil
+	h.mu.Unlock()
+	if !configured {
+		return errors.New("no proxy configured")
+	}
+
+	res, err := h.RequestWithContext(ctx, "GET", testURL)
+	if err != nil {
+		if res != nil {
+			res.Close()
+		}
+		if errors.Is(err, ErrProxyFailure) {
+			return err
+		}
+		return &RequestError{Kind: ErrProxyFailure, Err: fmt.Errorf("proxy check %s: %w", testURL, err)}
+	}
+	defer res.Drain()
+	if err := res.Error(); err != nil {
+		return &RequestError{Kind: ErrProxyFailure, Err: fmt.Errorf("proxy check %s: %w", testURL, err)}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package minireq
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// wsReservedHeaders Set by the websocket handshake itself
+var wsReservedHeaders = []string{
+	"Upgrade",
+	"Connection",
+	"Content-Length",
+	"Content-Type",
+	"Sec-Websocket-Key",
+	"Sec-Websocket-Version",
+	"Sec-Websocket-Extensions",
+}
+
+// WebSocket Open a websocket with the client's proxy, TLS and option settings
+func (h *HttpClient) WebSocket(url string, opts ...any) (*websocket.Conn, *MiniResponse, error) {
+	return h.WebSocketWithContext(h.baseContext(), url, opts...)
+}
+
+// WebSocketWithContext Open a websocket bound to ctx
+//
+// The dialer is derived from the transport plain requests use, so proxies,
+// TLS settings and SetTLSHandshake apply, and cookies go through the client
+// jar. A transport from SetTransport must be an *http.Transport.
+func (h *HttpClient) WebSocketWithContext(ctx context.Context, url string, opts ...any) (*websocket.Conn, *MiniResponse, error) {
+	request, err := h.buildRequest(ctx, "GET", url, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	header := request.Header.Clone()
+	for _, key := range wsReservedHeaders {
+		header.Del(key)
+	}
+
+	dialer, err := h.wsDialer(request, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conn, response, err := dialer.DialContext(ctx, request.URL.String(), header)
+	var miniRes *MiniResponse
+	if response != nil {
+		miniRes = &MiniResponse{Request: request, Response: response}
+	}
+	if err != nil {
+		return nil, miniRes, classifyError(err)
+	}
+	return conn, miniRes, nil
+}
+
+// wsDialer websocket dialer with the settings of the transport for request
+func (h *HttpClient) wsDialer(request *http.Request, opts []any) (*websocket.Dialer, error) {
+	transport, err := h.getTransport(request.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := h.Timeout
+	if timeout == 0 {
+		timeout = 30
+	}
+	dialer := &websocket.Dialer{
+		HandshakeTimeout: time.Duration(timeout) * time.Second,
+	}
+	if h2c, ok := transport.(*h2cTransport); ok {
+		transport = h2c.fallback
+	}
+	switch t := transport.(type) {
+	case *http.Transport:
+		dialer.Proxy = t.Proxy
+		dialer.NetDialContext = t.DialContext
+		dialer.NetDialTLSContext = t.DialTLSContext
+		dialer.TLSClientConfig = t.TLSClientConfig
+	case *orderedTransport:
+		dialer.NetDialContext = t.dialContext
+		dialer.TLSClientConfig = t.tlsConfig
+		if t.handshake != nil {
+			dialer.NetDialTLSContext = tlsDialContext(t.dialContext, *t.handshake, t.tlsConfig.ServerName, t.tlsConfig.InsecureSkipVerify)
+		}
+	default:
+		return nil, fmt.Errorf("websocket: unsupported transport %T", transport)
+	}
+
+	jar, err := h.cookieJar()
+	if err != nil {
+		return nil, err
+	}
+	dialer.Jar = jar
+	for _, opt := range opts {
+		if off, ok := opt.(NoCookies); ok && bool(off) {
+			dialer.Jar = nil
+		}
+	}
+	return dialer, nil
+}